@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database schema",
+	Long: `Inspect and apply database schema migrations.
+
+Cortex applies pending migrations automatically when it opens a store;
+these commands let you check and apply them explicitly.
+
+Examples:
+  cortex db status
+  cortex db status --store team
+  cortex db migrate --dry-run
+  cortex db migrate`,
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the schema version and pending migrations",
+	Args:  cobra.NoArgs,
+	RunE:  runDBStatus,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Args:  cobra.NoArgs,
+	RunE:  runDBMigrate,
+}
+
+var (
+	dbStore         string
+	dbMigrateDryRun bool
+)
+
+func init() {
+	dbCmd.PersistentFlags().StringVarP(&dbStore, "store", "s", "", "Store to inspect or migrate (default: config default_store)")
+	dbMigrateCmd.Flags().BoolVar(&dbMigrateDryRun, "dry-run", false, "List pending migrations without applying them")
+
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbMigrateCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// openDatabase opens the database of the store chosen with --store, without
+// migrating it
+func openDatabase() (*db.DB, types.StoreConfig, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, types.StoreConfig{}, err
+	}
+
+	sc, err := core.FindStore(cfg, dbStore)
+	if err != nil {
+		return nil, sc, err
+	}
+
+	if _, err := os.Stat(sc.Path); err != nil {
+		return nil, sc, fmt.Errorf("database of store %s not found: %s", sc.Name, sc.Path)
+	}
+
+	database, err := db.Open(sc.Path)
+	return database, sc, err
+}
+
+func runDBStatus(cmd *cobra.Command, args []string) error {
+	database, _, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	version, err := database.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	status, err := database.MigrationStatus()
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	if verbose {
+		printJSON(status)
+		return nil
+	}
+
+	fmt.Printf("Schema version: %d (latest: %d)\n\n", version, db.LatestSchemaVersion())
+
+	pending := 0
+	for _, s := range status {
+		state := "pending"
+		if s.Applied {
+			state = "applied " + s.AppliedAt.Format("2006-01-02 15:04")
+		} else {
+			pending++
+		}
+		fmt.Printf("  %3d  %-30s %s\n", s.Version, s.Name, state)
+	}
+
+	if pending > 0 {
+		fmt.Printf("\n%d pending migration(s). Run 'cortex db migrate' to apply.\n", pending)
+	}
+
+	return nil
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) error {
+	database, sc, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if dbMigrateDryRun {
+		pending, err := database.PendingMigrations()
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			fmt.Println("Schema is up to date.")
+			return nil
+		}
+		fmt.Println("Would apply:")
+		for _, m := range pending {
+			fmt.Printf("  %3d  %s\n", m.Version, m.Name)
+		}
+		return nil
+	}
+
+	if sc.ReadOnly {
+		return fmt.Errorf("store %s is read-only", sc.Name)
+	}
+
+	applied, err := database.Migrate()
+	for _, m := range applied {
+		fmt.Printf("✓ Applied %3d  %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("Schema is up to date.")
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and merge duplicate memories",
+	Long: `Group memories that duplicate each other and merge each group into one.
+
+Memories are compared by embedding similarity and by the overlap of their
+words. Each group is shown for review with the suggested survivor first
+(highest trust, then most used, then oldest); answer y to merge into it, a
+number to merge into another member, n to skip or q to stop.
+
+A merge gives the survivor the tags of the whole group, its highest trust
+and the sum of its access counts, and moves their relations to it. The
+merged-away memories are kept as obsolete, with a "replaces" relation from
+the survivor, so recall no longer returns them.
+
+Examples:
+  cortex dedupe
+  cortex dedupe --threshold 0.9 --dry-run
+  cortex dedupe --auto --threshold 0.95`,
+	RunE: runDedupe,
+}
+
+var (
+	dedupeStore     string
+	dedupeThreshold float64
+	dedupeAuto      bool
+	dedupeDryRun    bool
+)
+
+func init() {
+	dedupeCmd.Flags().StringVarP(&dedupeStore, "store", "s", "", "Store to deduplicate (default: config default_store)")
+	dedupeCmd.Flags().Float64Var(&dedupeThreshold, "threshold", core.DefaultClusterThreshold, "Similarity at which memories are duplicates (0-1)")
+	dedupeCmd.Flags().BoolVar(&dedupeAuto, "auto", false, "Merge every group into its suggested survivor without asking")
+	dedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "Only show the groups")
+	rootCmd.AddCommand(dedupeCmd)
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	clusters, err := engine.FindClusters(core.ClusterOptions{
+		Store:     dedupeStore,
+		Threshold: dedupeThreshold,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %w", err)
+	}
+
+	if verbose && dedupeDryRun {
+		printJSON(clusters)
+		return nil
+	}
+	if len(clusters) == 0 {
+		fmt.Println("No duplicates found.")
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	merged, groups := 0, 0
+
+	for i, c := range clusters {
+		fmt.Printf("\nGroup %d of %d: %d memories, similarity ≥ %.0f%%\n", i+1, len(clusters), len(c.Memories), c.Similarity*100)
+		for j, m := range c.Memories {
+			marker := " "
+			if j == 0 {
+				marker = "*"
+			}
+			fmt.Printf("  %s [%d] %s  %s, %s, %d uses\n", marker, j+1, m.ID, m.Type, m.Trust, m.AccessCnt)
+			if m.TopicKey != "" {
+				fmt.Printf("        Topic: %s\n", m.TopicKey)
+			}
+			fmt.Printf("        %s\n", truncate(m.Content, 120))
+		}
+
+		if dedupeDryRun {
+			continue
+		}
+
+		survivor := 0
+		if !dedupeAuto {
+			fmt.Printf("Merge into [1]? (y/N/1-%d/q): ", len(c.Memories))
+			response, err := reader.ReadString('\n')
+			response = strings.TrimSpace(strings.ToLower(response))
+			if response == "q" || (err != nil && response == "") {
+				break
+			}
+			if n, err := strconv.Atoi(response); err == nil && n >= 1 && n <= len(c.Memories) {
+				survivor = n - 1
+			} else if response != "y" && response != "yes" {
+				fmt.Println("Skipped.")
+				continue
+			}
+		}
+
+		var duplicateIDs []string
+		for j, m := range c.Memories {
+			if j != survivor {
+				duplicateIDs = append(duplicateIDs, m.ID)
+			}
+		}
+
+		memory, err := engine.Merge(c.Memories[survivor].ID, duplicateIDs)
+		if err != nil {
+			return fmt.Errorf("failed to merge group %d: %w", i+1, err)
+		}
+		fmt.Printf("✓ Merged %d into %s\n", len(duplicateIDs), memory.ID)
+		merged += len(duplicateIDs)
+		groups++
+	}
+
+	if dedupeDryRun {
+		fmt.Printf("\n%d groups found. Run without --dry-run to merge them.\n", len(clusters))
+		return nil
+	}
+	fmt.Printf("\n✓ Merged %d memories in %d groups\n", merged, groups)
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <id|topic-key> <rev1> <rev2>",
+	Short: "Compare two revisions of a memory",
+	Long: `Show the differences between two revisions of a memory.
+
+Use 'cortex history' to list the available revisions.
+
+Examples:
+  cortex diff abc123def456 1 3
+  cortex diff architecture/database 2 4`,
+	Args: cobra.ExactArgs(3),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	rev1, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid revision: %s", args[1])
+	}
+	rev2, err := strconv.Atoi(args[2])
+	if err != nil {
+		return fmt.Errorf("invalid revision: %s", args[2])
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	memory, err := engine.Resolve(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory == nil {
+		return fmt.Errorf("memory not found: %s", args[0])
+	}
+
+	from, err := engine.Revision(memory.ID, rev1)
+	if err != nil {
+		return err
+	}
+	to, err := engine.Revision(memory.ID, rev2)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("--- %s\n", formatRevision(from))
+	fmt.Printf("+++ %s\n", formatRevision(to))
+
+	if from.Type != to.Type {
+		fmt.Printf("Type:  %s → %s\n", from.Type, to.Type)
+	}
+	if from.Trust != to.Trust {
+		fmt.Printf("Trust: %s → %s\n", from.Trust, to.Trust)
+	}
+	if strings.Join(from.Tags, ",") != strings.Join(to.Tags, ",") {
+		fmt.Printf("Tags:  [%s] → [%s]\n", strings.Join(from.Tags, ", "), strings.Join(to.Tags, ", "))
+	}
+
+	fmt.Println()
+	for _, line := range diffLines(from.Content, to.Content) {
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// diffLines returns a line-based diff of two texts, each line prefixed with
+// "  " (unchanged), "- " (removed) or "+ " (added)
+func diffLines(a, b string) []string {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, "  "+x[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, "- "+x[i])
+	}
+	for ; j < len(y); j++ {
+		out = append(out, "+ "+y[j])
+	}
+
+	return out
+}
+
+// formatRevision renders a revision header line
+func formatRevision(r *types.Revision) string {
+	return fmt.Sprintf("rev %d (%s, %s)", r.Rev, r.CreatedAt.Format("2006-01-02 15:04"), r.Author)
+}
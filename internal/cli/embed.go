@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed",
+	Short: "Retry embeddings that failed",
+	Long: `Embed memories that were stored while the embedding provider failed.
+
+Such memories wait in a retry queue with growing delays between attempts.
+Cortex retries a few of them whenever it starts and while the MCP server is
+idle; --pending retries the whole queue now, ignoring the delays.
+
+Examples:
+  cortex embed --pending`,
+	RunE: runEmbed,
+}
+
+var embedPending bool
+
+func init() {
+	embedCmd.Flags().BoolVar(&embedPending, "pending", false, "Retry every queued embedding now")
+	rootCmd.AddCommand(embedCmd)
+}
+
+func runEmbed(cmd *cobra.Command, args []string) error {
+	if !embedPending {
+		return cmd.Help()
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	stats, err := engine.EmbedPending(context.Background(), core.PendingOptions{Force: true})
+	if err != nil {
+		return fmt.Errorf("failed to embed pending memories: %w", err)
+	}
+
+	if verbose {
+		printJSON(stats)
+		return nil
+	}
+
+	fmt.Printf("✓ Embedded %d memories\n", stats.Embedded)
+	if stats.Failed > 0 {
+		fmt.Printf("  Failed: %d (will be retried later)\n", stats.Failed)
+	}
+	if stats.Remaining > 0 {
+		fmt.Printf("  Still pending: %d\n", stats.Remaining)
+	}
+
+	return nil
+}
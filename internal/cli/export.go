@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/constantino-dev/cortex/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export memories to a JSONL file",
+	Long: `Export memories, revisions and relations as JSON Lines.
+
+The output can be loaded into another store with 'cortex import'.
+With --embeddings the vectors are included along with their model name,
+so an import into a store using the same model needs no API calls.
+
+Examples:
+  cortex export > backup.jsonl
+  cortex export -o team.jsonl --embeddings
+  cortex export --key "react/" --trust validated,proven -o react.jsonl`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+var (
+	exportOutput     string
+	exportEmbeddings bool
+	exportTypes      string
+	exportTrust      string
+	exportTopicKey   string
+)
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportEmbeddings, "embeddings", false, "Include embedding vectors")
+	exportCmd.Flags().StringVarP(&exportTypes, "type", "t", "", "Only export these type(s), comma-separated")
+	exportCmd.Flags().StringVar(&exportTrust, "trust", "", "Only export these trust level(s), comma-separated")
+	exportCmd.Flags().StringVarP(&exportTopicKey, "key", "k", "", "Only export this topic key prefix")
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	opts := core.ExportOptions{
+		Embeddings: exportEmbeddings,
+		Filter:     types.RecallOptions{TopicKey: exportTopicKey},
+	}
+	if exportTypes != "" {
+		for _, t := range strings.Split(exportTypes, ",") {
+			opts.Filter.Types = append(opts.Filter.Types, types.MemoryType(strings.TrimSpace(t)))
+		}
+	}
+	if exportTrust != "" {
+		for _, t := range strings.Split(exportTrust, ",") {
+			opts.Filter.TrustLevels = append(opts.Filter.TrustLevels, types.TrustLevel(strings.TrimSpace(t)))
+		}
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	var w io.Writer = os.Stdout
+	if exportOutput != "" {
+		f, err := os.Create(exportOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	stats, err := engine.Export(w, opts)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	// Keep stdout clean for the export itself
+	fmt.Fprintf(os.Stderr, "✓ Exported %d memories, %d revisions, %d relations, %d embeddings\n",
+		stats.Memories, stats.Revisions, stats.Relations, stats.Embeddings)
+
+	return nil
+}
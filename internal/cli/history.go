@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id|topic-key>",
+	Short: "Show the revision history of a memory",
+	Long: `Show how a memory evolved through topic-key updates.
+
+Examples:
+  cortex history abc123def456
+  cortex history architecture/database`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	memory, err := engine.Resolve(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory == nil {
+		return fmt.Errorf("memory not found: %s", args[0])
+	}
+
+	revisions, err := engine.History(memory.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+
+	if verbose {
+		printJSON(revisions)
+		return nil
+	}
+
+	fmt.Printf("History of %s", memory.ID)
+	if memory.TopicKey != "" {
+		fmt.Printf(" (topic: %s)", memory.TopicKey)
+	}
+	fmt.Println()
+
+	if len(revisions) == 0 {
+		fmt.Println("\nNo revisions recorded. Revisions are kept for memories stored with a topic key.")
+		return nil
+	}
+
+	fmt.Printf("\n%-4s %-17s %-16s %-10s %-10s %s\n", "REV", "DATE", "AUTHOR", "TRUST", "TYPE", "CONTENT")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, r := range revisions {
+		author := r.Author
+		if len(author) > 16 {
+			author = author[:13] + "..."
+		}
+		fmt.Printf("%-4d %-17s %-16s %-10s %-10s %s\n",
+			r.Rev, r.CreatedAt.Format("2006-01-02 15:04"), author, r.Trust, r.Type, truncate(r.Content, 40))
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import memories from a JSONL export",
+	Long: `Import memories, revisions and relations from a 'cortex export' file.
+
+Memories whose topic key already exists are merged: the newer version wins
+and the change is recorded as a revision. Relations are re-pointed to the
+merged memories. Embeddings from the export are reused when they come from
+the same model as this store; otherwise the memories are re-embedded.
+
+Use "-" to read from stdin.
+
+Examples:
+  cortex import backup.jsonl
+  cortex import team.jsonl --remap-ids
+  cat team.jsonl | cortex import -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+var (
+	importRemapIDs bool
+	importStore    string
+)
+
+func init() {
+	importCmd.Flags().BoolVar(&importRemapIDs, "remap-ids", false, "Assign new IDs instead of keeping the exported ones")
+	importCmd.Flags().StringVarP(&importStore, "store", "s", "", "Store to import into (default: config default_store)")
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	engine, err := getEngineForStore(importStore)
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	stats, err := engine.Import(context.Background(), r, core.ImportOptions{RemapIDs: importRemapIDs})
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	if verbose {
+		printJSON(stats)
+		return nil
+	}
+
+	fmt.Printf("✓ Imported %d memories (%d updated, %d unchanged)\n", stats.Memories, stats.Updated, stats.Skipped)
+	fmt.Printf("  Revisions:  %d\n", stats.Revisions)
+	fmt.Printf("  Relations:  %d\n", stats.Relations)
+	fmt.Printf("  Embeddings: %d copied, %d re-embedded\n", stats.Embeddings, stats.Reembedded)
+
+	return nil
+}
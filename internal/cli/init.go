@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/constantino-dev/cortex/internal/embeddings"
 	"github.com/constantino-dev/cortex/pkg/types"
 	"github.com/spf13/cobra"
 )
@@ -16,18 +17,32 @@ var initCmd = &cobra.Command{
 	Short: "Initialize a new Cortex memory store",
 	Long: `Initialize a new Cortex memory store in the current directory.
 
-This creates a .cortex directory with configuration and database files.`,
+This creates a .cortex directory with configuration and database files.
+
+Examples:
+  cortex init
+  cortex init --provider ollama
+  cortex init --provider ollama --ollama-model mxbai-embed-large
+  cortex init --team-store ../team-knowledge --global-store`,
 	RunE: runInit,
 }
 
 var (
-	initOpenAIKey string
-	initProvider  string
+	initOpenAIKey   string
+	initProvider    string
+	initOllamaURL   string
+	initOllamaModel string
+	initTeamStore   string
+	initGlobalStore bool
 )
 
 func init() {
 	initCmd.Flags().StringVar(&initOpenAIKey, "openai-key", "", "OpenAI API key")
 	initCmd.Flags().StringVar(&initProvider, "provider", "openai", "Embedding provider (openai, ollama)")
+	initCmd.Flags().StringVar(&initOllamaURL, "ollama-url", embeddings.DefaultOllamaURL, "Ollama server URL")
+	initCmd.Flags().StringVar(&initOllamaModel, "ollama-model", embeddings.DefaultOllamaModel, "Ollama embedding model")
+	initCmd.Flags().StringVar(&initTeamStore, "team-store", "", "Path of a shared team store (e.g., a git-tracked directory)")
+	initCmd.Flags().BoolVar(&initGlobalStore, "global-store", false, "Also search the global store in ~/.cortex")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -69,6 +84,17 @@ func runInit(cmd *cobra.Command, args []string) error {
 		EmbeddingProvider: initProvider,
 		OpenAIKey:         apiKey,
 	}
+	if initProvider == "ollama" {
+		cfg.OpenAIKey = ""
+		cfg.OllamaURL = initOllamaURL
+		cfg.OllamaModel = initOllamaModel
+	}
+	if initTeamStore != "" {
+		cfg.Stores = append(cfg.Stores, types.StoreConfig{Name: "team", Path: initTeamStore})
+	}
+	if initGlobalStore {
+		cfg.Stores = append(cfg.Stores, types.StoreConfig{Name: "global", Path: "~/" + configDir})
+	}
 
 	// Save config
 	if err := saveConfig(cfg); err != nil {
@@ -87,6 +113,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 	fmt.Println("✓ Cortex initialized successfully")
 	fmt.Printf("  Config: %s\n", filepath.Join(configPath, configFile))
 	fmt.Printf("  Database: %s\n", cfg.DBPath)
+	for _, sc := range cfg.Stores {
+		fmt.Printf("  Store %s: %s\n", sc.Name, sc.Path)
+	}
 
 	return nil
 }
@@ -18,16 +18,18 @@ Examples:
   cortex list
   cortex list --type error
   cortex list --limit 20
-  cortex list --project my-project`,
+  cortex list --project my-project
+  cortex list --tags react,hooks`,
 	RunE: runList,
 }
 
 var (
-	listLimit   int
-	listTypes   string
-	listTrust   string
-	listProject string
+	listLimit    int
+	listTypes    string
+	listTrust    string
+	listProject  string
 	listTopicKey string
+	listTags     string
 )
 
 func init() {
@@ -36,6 +38,7 @@ func init() {
 	listCmd.Flags().StringVar(&listTrust, "trust", "", "Filter by trust level")
 	listCmd.Flags().StringVar(&listProject, "project", "", "Filter by project")
 	listCmd.Flags().StringVarP(&listTopicKey, "key", "k", "", "Filter by topic key prefix")
+	listCmd.Flags().StringVar(&listTags, "tags", "", "Filter by tags, comma-separated (matches any)")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -60,6 +63,13 @@ func runList(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Parse tags
+	if listTags != "" {
+		for _, tag := range strings.Split(listTags, ",") {
+			opts.Tags = append(opts.Tags, strings.TrimSpace(tag))
+		}
+	}
+
 	// Create engine
 	engine, err := getEngine()
 	if err != nil {
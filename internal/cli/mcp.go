@@ -1,7 +1,13 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/constantino-dev/cortex/internal/mcp"
 	"github.com/spf13/cobra"
@@ -14,7 +20,9 @@ var mcpCmd = &cobra.Command{
 
 This allows AI agents to use Cortex as an external memory system.
 
-The server communicates over stdio using JSON-RPC.
+The server communicates over stdio using JSON-RPC. With --http it serves
+the MCP Streamable HTTP transport instead, at http://<addr>/mcp, so several
+agents and editor windows can share one server and one database.
 
 Example usage with Claude Desktop:
   Add to claude_desktop_config.json:
@@ -25,11 +33,21 @@ Example usage with Claude Desktop:
         "args": ["mcp", "-p", "/path/to/project"]
       }
     }
-  }`,
+  }
+
+Shared over HTTP:
+  cortex mcp --http :7777 --token secret`,
 	RunE: runMCP,
 }
 
+var (
+	mcpHTTP  string
+	mcpToken string
+)
+
 func init() {
+	mcpCmd.Flags().StringVar(&mcpHTTP, "http", "", "Serve over HTTP on this address (e.g. :7777) instead of stdio")
+	mcpCmd.Flags().StringVar(&mcpToken, "token", "", "Bearer token HTTP clients must send (default: $CORTEX_MCP_TOKEN)")
 	rootCmd.AddCommand(mcpCmd)
 }
 
@@ -41,5 +59,42 @@ func runMCP(cmd *cobra.Command, args []string) error {
 	defer engine.Close()
 
 	server := mcp.NewServer(engine)
-	return server.Run()
+	if mcpHTTP == "" {
+		return server.Run()
+	}
+
+	token := mcpToken
+	if token == "" {
+		token = os.Getenv("CORTEX_MCP_TOKEN")
+	}
+	if token == "" && !isLoopback(mcpHTTP) {
+		fmt.Fprintln(os.Stderr, "warning: no --token set; anyone who can reach this address can read and write memories")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Cortex MCP server listening on http://%s/mcp\n", displayAddr(mcpHTTP))
+	return server.RunHTTP(ctx, mcp.HTTPOptions{Addr: mcpHTTP, Token: token})
+}
+
+// isLoopback reports whether a listen address only accepts local connections
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// displayAddr fills in the host of an address like ":7777"
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
 }
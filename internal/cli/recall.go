@@ -18,7 +18,11 @@ Examples:
   cortex recall "how to handle async errors"
   cortex recall "react hooks" --limit 10
   cortex recall "migration patterns" --type pattern
-  cortex recall "database decisions" --include-proposed`,
+  cortex recall "state management" --tags react,redux --key frontend/
+  cortex recall "database decisions" --include-proposed
+  cortex recall "ECONNREFUSED on startup" --rerank
+  cortex recall "retry strategy" --explain
+  cortex recall "build failures" --mmr-lambda 0.5`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runRecall,
 }
@@ -28,17 +32,25 @@ var (
 	recallTypes           string
 	recallTags            string
 	recallProject         string
+	recallTopicKey        string
 	recallIncludeProposed bool
 	recallMinScore        float64
+	recallRerank          bool
+	recallExplain         bool
+	recallMMRLambda       float64
 )
 
 func init() {
 	recallCmd.Flags().IntVarP(&recallLimit, "limit", "n", 5, "Maximum results to return")
 	recallCmd.Flags().StringVarP(&recallTypes, "type", "t", "", "Filter by type(s), comma-separated")
-	recallCmd.Flags().StringVar(&recallTags, "tags", "", "Filter by tags, comma-separated")
+	recallCmd.Flags().StringVar(&recallTags, "tags", "", "Filter by tags, comma-separated (matches any)")
 	recallCmd.Flags().StringVar(&recallProject, "project", "", "Filter by project")
+	recallCmd.Flags().StringVarP(&recallTopicKey, "key", "k", "", "Filter by topic key prefix")
 	recallCmd.Flags().BoolVar(&recallIncludeProposed, "include-proposed", false, "Include proposed (unvalidated) memories")
-	recallCmd.Flags().Float64Var(&recallMinScore, "min-score", 0.3, "Minimum relevance score (0-1)")
+	recallCmd.Flags().Float64Var(&recallMinScore, "min-score", 0.3, "Minimum similarity of memories found without a keyword match (0-1)")
+	recallCmd.Flags().BoolVar(&recallRerank, "rerank", false, "Rescore the top candidates with the configured reranker")
+	recallCmd.Flags().BoolVar(&recallExplain, "explain", false, "Show how each factor contributed to the score")
+	recallCmd.Flags().Float64Var(&recallMMRLambda, "mmr-lambda", 0, "Diversify results by MMR (0-1, lower favors variety; 0 disables)")
 }
 
 func runRecall(cmd *cobra.Command, args []string) error {
@@ -46,9 +58,13 @@ func runRecall(cmd *cobra.Command, args []string) error {
 
 	// Build options
 	opts := types.RecallOptions{
-		Limit:    recallLimit,
-		MinScore: recallMinScore,
-		Project:  recallProject,
+		Limit:     recallLimit,
+		MinScore:  recallMinScore,
+		Project:   recallProject,
+		TopicKey:  recallTopicKey,
+		Rerank:    recallRerank,
+		Explain:   recallExplain,
+		MMRLambda: recallMMRLambda,
 	}
 
 	// Parse types
@@ -111,16 +127,62 @@ func runRecall(cmd *cobra.Command, args []string) error {
 				fmt.Printf("    Topic: %s\n", r.Memory.TopicKey)
 			}
 			fmt.Printf("    Trust: %s\n", r.Memory.Trust)
+			if len(engine.Stores()) > 1 {
+				fmt.Printf("    Store: %s\n", r.Memory.Store)
+			}
 			fmt.Printf("    Content: %s\n", truncate(r.Memory.Content, 200))
 			if len(r.Memory.Tags) > 0 {
 				fmt.Printf("    Tags: %s\n", strings.Join(r.Memory.Tags, ", "))
 			}
+			if r.Explanation != nil {
+				printExplanation(r)
+			}
+		}
+		if ex := results[0].Explanation; ex != nil {
+			printFilters(ex.Filters)
 		}
 	}
 
 	return nil
 }
 
+// printExplanation shows the retrieval evidence behind a result and each
+// factor's value and what it added to the score
+func printExplanation(r types.SearchResult) {
+	ex := r.Explanation
+	fmt.Printf("    Score: %.3f (%s match)\n", r.Score, r.MatchType)
+	if ex.SemanticRank > 0 || ex.Distance != nil {
+		fmt.Printf("      vector:  ")
+		if ex.Distance != nil {
+			fmt.Printf("distance %.3f, ", *ex.Distance)
+		}
+		fmt.Printf("similarity %.2f", ex.Semantic)
+		if ex.SemanticRank > 0 {
+			fmt.Printf(", rank %d", ex.SemanticRank)
+		} else {
+			fmt.Printf(" (not in vector results)")
+		}
+		fmt.Println()
+	}
+	if ex.KeywordRank > 0 {
+		fmt.Printf("      keyword: FTS rank %d, bm25 %.3g, boost %.2f\n", ex.KeywordRank, ex.BM25, ex.Keyword)
+	}
+	if ex.StoreWeight != 1 {
+		fmt.Printf("      store weight: %.2f\n", ex.StoreWeight)
+	}
+	for _, f := range ex.Factors {
+		fmt.Printf("      %-10s %.2f  +%.3f\n", f.Name, f.Value, f.Contribution)
+	}
+}
+
+// printFilters shows the filters of a recall and how many candidates each dropped
+func printFilters(filters []types.FilterStat) {
+	fmt.Println("\nFilters:")
+	for _, f := range filters {
+		fmt.Printf("  %-10s %-20s dropped %d\n", f.Name, f.Value, f.Dropped)
+	}
+}
+
 func formatType(t types.MemoryType) string {
 	switch t {
 	case types.TypeError:
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var reembedCmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Re-embed all memories with the configured model",
+	Long: `Re-embed all memories with the configured embedding provider and rebuild
+the vector index at the provider's dimension.
+
+Run this after switching embedding provider or model in .cortex/config.json.
+Memories already embedded with the current model are skipped, so an
+interrupted run can simply be started again.
+
+Examples:
+  cortex reembed
+  cortex reembed --batch-size 16`,
+	RunE: runReembed,
+}
+
+var reembedBatchSize int
+
+func init() {
+	reembedCmd.Flags().IntVar(&reembedBatchSize, "batch-size", core.DefaultReembedBatchSize, "Memories per embedding request")
+	rootCmd.AddCommand(reembedCmd)
+}
+
+func runReembed(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	engine, err := core.NewForReembed(cfg)
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	lastStore := ""
+	results, err := engine.Reembed(ctx, reembedBatchSize, func(p core.ReembedProgress) {
+		if lastStore != "" && p.Store != lastStore {
+			fmt.Println()
+		}
+		lastStore = p.Store
+		fmt.Printf("\r[%s] Re-embedding: %d/%d", p.Store, p.Done, p.Total)
+	})
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("re-embed stopped: %w\nRun 'cortex reembed' again to resume", err)
+	}
+
+	for _, r := range results {
+		fmt.Printf("✓ [%s] Re-embedded %d memories", r.Store, r.Done)
+		if r.Restored > 0 {
+			fmt.Printf(" (%d already up to date)", r.Restored)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var revertCmd = &cobra.Command{
+	Use:   "revert <id|topic-key> <rev>",
+	Short: "Restore a memory to a past revision",
+	Long: `Restore a memory's content, type, tags and trust from a past revision.
+
+The revert is recorded as a new revision, so it can itself be undone.
+
+Examples:
+  cortex revert abc123def456 2
+  cortex revert architecture/database 1`,
+	Args: cobra.ExactArgs(2),
+	RunE: runRevert,
+}
+
+func init() {
+	rootCmd.AddCommand(revertCmd)
+}
+
+func runRevert(cmd *cobra.Command, args []string) error {
+	rev, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid revision: %s", args[1])
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	memory, err := engine.Resolve(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get memory: %w", err)
+	}
+	if memory == nil {
+		return fmt.Errorf("memory not found: %s", args[0])
+	}
+
+	memory, err = engine.Revert(context.Background(), memory.ID, rev, "cli:revert")
+	if err != nil {
+		return fmt.Errorf("failed to revert: %w", err)
+	}
+
+	if verbose {
+		printJSON(memory)
+	} else {
+		fmt.Printf("✓ Reverted %s to revision %d\n", memory.ID, rev)
+	}
+
+	return nil
+}
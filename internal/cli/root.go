@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/constantino-dev/cortex/internal/core"
 	"github.com/constantino-dev/cortex/pkg/types"
@@ -92,9 +93,37 @@ func loadConfig() (*types.Config, error) {
 		cfg.DBPath = filepath.Join(getConfigPath(), dbFile)
 	}
 
+	for i := range cfg.Stores {
+		cfg.Stores[i].Path = resolveStorePath(cfg.Stores[i].Path)
+		if dir := cfg.Stores[i].SyncDir; dir != "" && !filepath.IsAbs(dir) {
+			cfg.Stores[i].SyncDir = filepath.Join(getProjectDir(), dir)
+		}
+	}
+
 	return &cfg, nil
 }
 
+// resolveStorePath expands "~", makes relative paths relative to the project
+// directory, and points directories (such as a git-tracked team folder) at
+// the cortex.db inside them
+func resolveStorePath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(getProjectDir(), path)
+	}
+
+	if info, err := os.Stat(path); (err == nil && info.IsDir()) || filepath.Ext(path) == "" {
+		path = filepath.Join(path, dbFile)
+	}
+
+	return path
+}
+
 // saveConfig saves the configuration
 func saveConfig(cfg *types.Config) error {
 	configPath := filepath.Join(getConfigPath(), configFile)
@@ -109,11 +138,21 @@ func saveConfig(cfg *types.Config) error {
 
 // getEngine creates and returns a Cortex engine
 func getEngine() (*core.Engine, error) {
+	return getEngineForStore("")
+}
+
+// getEngineForStore creates an engine that writes to the named store
+// instead of the configured default
+func getEngineForStore(store string) (*core.Engine, error) {
 	cfg, err := loadConfig()
 	if err != nil {
 		return nil, err
 	}
 
+	if store != "" {
+		cfg.DefaultStore = store
+	}
+
 	return core.New(cfg)
 }
 
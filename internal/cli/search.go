@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search memories by keyword",
+	Long: `Search memories by keyword using the full-text index (BM25 ranking).
+
+Unlike recall, search needs no embedding call and matches exact tokens,
+which suits error messages, identifiers and stack traces. By default any
+word of the query may match and punctuation is ignored.
+
+With --fts the query uses an explicit syntax:
+  word              the word must appear
+  "exact phrase"    the words must appear in this order
+  pref*             a word starting with pref
+  -word             the word must not appear
+  a OR b            either term
+  topic_key:term    match in the topic key only (e.g. topic_key:"react/hooks"*)
+  tags:term         match in the tags only
+  content:term      match in the content only
+
+Examples:
+  cortex search "ECONNREFUSED 127.0.0.1:5432"
+  cortex search --fts '"connection refused" postgres* -docker'
+  cortex search --fts 'tags:auth OR tags:security topic_key:api*'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+var (
+	searchFTS      bool
+	searchLimit    int
+	searchTypes    string
+	searchTags     string
+	searchTrust    string
+	searchTopicKey string
+)
+
+func init() {
+	searchCmd.Flags().BoolVar(&searchFTS, "fts", false, "Use the advanced query syntax")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 10, "Maximum results to return")
+	searchCmd.Flags().StringVarP(&searchTypes, "type", "t", "", "Filter by type(s), comma-separated")
+	searchCmd.Flags().StringVar(&searchTags, "tags", "", "Filter by tags, comma-separated (matches any)")
+	searchCmd.Flags().StringVar(&searchTrust, "trust", "", "Filter by trust level(s), comma-separated")
+	searchCmd.Flags().StringVarP(&searchTopicKey, "key", "k", "", "Filter by topic key prefix")
+	rootCmd.AddCommand(searchCmd)
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+
+	opts := types.RecallOptions{
+		Limit:    searchLimit,
+		TopicKey: searchTopicKey,
+	}
+	if searchTypes != "" {
+		for _, t := range strings.Split(searchTypes, ",") {
+			opts.Types = append(opts.Types, types.MemoryType(strings.TrimSpace(t)))
+		}
+	}
+	if searchTags != "" {
+		for _, tag := range strings.Split(searchTags, ",") {
+			opts.Tags = append(opts.Tags, strings.TrimSpace(tag))
+		}
+	}
+	if searchTrust != "" {
+		for _, t := range strings.Split(searchTrust, ",") {
+			opts.TrustLevels = append(opts.TrustLevels, types.TrustLevel(strings.TrimSpace(t)))
+		}
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	results, err := engine.Search(query, searchFTS, opts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No memories found matching your query.")
+		return nil
+	}
+
+	if verbose {
+		printJSON(results)
+		return nil
+	}
+
+	for i, r := range results {
+		fmt.Printf("\n[%d] %s (%.0f%% of best match)\n", i+1, formatType(r.Memory.Type), r.Score*100)
+		fmt.Printf("    ID: %s\n", r.Memory.ID)
+		if r.Memory.TopicKey != "" {
+			fmt.Printf("    Topic: %s\n", r.Memory.TopicKey)
+		}
+		fmt.Printf("    Trust: %s\n", r.Memory.Trust)
+		if len(engine.Stores()) > 1 {
+			fmt.Printf("    Store: %s\n", r.Memory.Store)
+		}
+		fmt.Printf("    Content: %s\n", truncate(r.Memory.Content, 200))
+		if len(r.Memory.Tags) > 0 {
+			fmt.Printf("    Tags: %s\n", strings.Join(r.Memory.Tags, ", "))
+		}
+	}
+
+	return nil
+}
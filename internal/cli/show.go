@@ -52,6 +52,7 @@ func runShow(cmd *cobra.Command, args []string) error {
 		fmt.Printf("├─────────────────────────────────────────────────────────────┤\n")
 		fmt.Printf("│ Type:     %s\n", formatType(memory.Type))
 		fmt.Printf("│ Trust:    %s\n", memory.Trust)
+		fmt.Printf("│ Store:    %s\n", memory.Store)
 		if memory.TopicKey != "" {
 			fmt.Printf("│ Topic:    %s\n", memory.TopicKey)
 		}
@@ -36,7 +36,24 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Printf("Memories:   %d\n", stats["memories"])
 		fmt.Printf("Relations:  %d\n", stats["relations"])
 		fmt.Printf("Embeddings: %d\n", stats["embeddings"])
+		if stats["unembedded"] > 0 {
+			fmt.Printf("Unembedded: %d (%d queued for retry; run 'cortex embed --pending')\n", stats["unembedded"], stats["pending_embeddings"])
+		}
+		if stats["stores"] > 1 {
+			fmt.Printf("Stores:     %d\n", stats["stores"])
+		}
+		fmt.Println()
+		fmt.Printf("Embedding cache: %d entries, %s\n", stats["cache_entries"], hitRate(stats["cache_hits"], stats["cache_misses"]))
+		fmt.Printf("Query cache:     %s\n", hitRate(stats["query_cache_hits"], stats["query_cache_misses"]))
 	}
 
 	return nil
 }
+
+// hitRate describes how often a cache was hit
+func hitRate(hits, misses int) string {
+	if hits+misses == 0 {
+		return "not used yet"
+	}
+	return fmt.Sprintf("%.0f%% hit rate (%d hits, %d misses)", float64(hits)*100/float64(hits+misses), hits, misses)
+}
@@ -22,7 +22,9 @@ Examples:
   cortex store "React hooks must be called at top level"
   cortex store -t pattern -k "react/hooks/rules" "Don't use hooks in loops"
   echo "Important fact" | cortex store
-  cortex store --type error --tags "react,migration" "useState in loop causes issues"`,
+  cortex store --type error --tags "react,migration" "useState in loop causes issues"
+  cortex store --store team -t decision "All services log in JSON"
+  cortex store --force "Store this even if a near-duplicate exists"`,
 	RunE: runStore,
 }
 
@@ -33,6 +35,8 @@ var (
 	storeTrust    string
 	storeSource   string
 	storeProject  string
+	storeStore    string
+	storeForce    bool
 )
 
 func init() {
@@ -42,6 +46,8 @@ func init() {
 	storeCmd.Flags().StringVar(&storeTrust, "trust", "proposed", "Trust level (proposed, validated, proven)")
 	storeCmd.Flags().StringVar(&storeSource, "source", "cli", "Source of memory")
 	storeCmd.Flags().StringVar(&storeProject, "project", "", "Project scope")
+	storeCmd.Flags().StringVarP(&storeStore, "store", "s", "", "Store to write to (default: config default_store)")
+	storeCmd.Flags().BoolVar(&storeForce, "force", false, "Store even if a near-duplicate exists")
 }
 
 func runStore(cmd *cobra.Command, args []string) error {
@@ -80,20 +86,21 @@ func runStore(cmd *cobra.Command, args []string) error {
 	trust := types.TrustLevel(storeTrust)
 
 	// Create engine
-	engine, err := getEngine()
+	engine, err := getEngineForStore(storeStore)
 	if err != nil {
 		return err
 	}
 	defer engine.Close()
 
 	// Store memory
-	memory, err := engine.Store(context.Background(), content, types.StoreOptions{
-		Type:     memType,
-		TopicKey: storeTopicKey,
-		Tags:     tags,
-		Trust:    trust,
-		Source:   storeSource,
-		Project:  storeProject,
+	result, err := engine.Store(context.Background(), content, types.StoreOptions{
+		Type:           memType,
+		TopicKey:       storeTopicKey,
+		Tags:           tags,
+		Trust:          trust,
+		Source:         storeSource,
+		Project:        storeProject,
+		AllowDuplicate: storeForce,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to store: %w", err)
@@ -101,11 +108,34 @@ func runStore(cmd *cobra.Command, args []string) error {
 
 	// Output
 	if verbose {
-		printJSON(memory)
-	} else {
-		fmt.Printf("✓ Stored memory: %s\n", memory.ID)
-		if memory.TopicKey != "" {
-			fmt.Printf("  Topic: %s\n", memory.TopicKey)
+		printJSON(result)
+		return nil
+	}
+
+	switch result.Action {
+	case types.StoreRefused:
+		fmt.Printf("✗ Not stored: near-duplicate of %s\n", result.Duplicate.ID)
+	case types.StoreMerged:
+		fmt.Printf("✓ Merged into near-duplicate: %s\n", result.Duplicate.ID)
+	case types.StoreRevised:
+		fmt.Printf("✓ Stored as a new revision of near-duplicate: %s\n", result.Duplicate.ID)
+	default:
+		fmt.Printf("✓ Stored memory: %s\n", result.Memory.ID)
+		if storeStore != "" {
+			fmt.Printf("  Store: %s\n", storeStore)
+		}
+		if result.Memory.TopicKey != "" {
+			fmt.Printf("  Topic: %s\n", result.Memory.TopicKey)
+		}
+	}
+	if dup := result.Duplicate; dup != nil {
+		if result.Action == types.StoreRelated {
+			fmt.Printf("  Related to near-duplicate: %s\n", dup.ID)
+		}
+		fmt.Printf("  Similarity: %.0f%%\n", result.Similarity*100)
+		fmt.Printf("  Existing: %s\n", truncate(dup.Content, 200))
+		if result.Action == types.StoreRefused {
+			fmt.Println("Tip: Use --force to store it anyway.")
 		}
 	}
 
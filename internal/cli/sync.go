@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror a store to Markdown files for git",
+	Long: `Mirror memories and relations to one Markdown file per memory, so a
+store can be committed, reviewed in pull requests and merged like code.
+
+Each memory is written to <dir>/<topic-key>.md (or <id>.md without a topic
+key) with its fields and outgoing relations in a frontmatter header. The
+default directory is memories/ next to the store's database, e.g.
+.cortex/memories for the project store; set "sync_dir" on a store to change it.
+
+A sync first applies the files to the store, then rewrites the files from
+the store. Both sides are compared with the last sync: edited files update
+their memory (recording a revision), new files create memories, deleted
+files delete them, and local changes are written out. A memory changed on
+both sides keeps the newer version; the other stays in 'cortex history'.
+
+Use --rebuild after cloning or resolving a merge to make the store match the
+files exactly, re-embed every memory and rebuild the full-text index.
+
+Examples:
+  cortex sync
+  git pull && cortex sync --from-files
+  cortex sync --store team && git -C ../team-knowledge commit -am "knowledge"
+  cortex sync --rebuild`,
+	Args: cobra.NoArgs,
+	RunE: runSync,
+}
+
+var (
+	syncStore     string
+	syncDir       string
+	syncFromFiles bool
+	syncToFiles   bool
+	syncRebuild   bool
+)
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncStore, "store", "s", "", "Store to sync (default: config default_store)")
+	syncCmd.Flags().StringVarP(&syncDir, "dir", "d", "", "Directory of Markdown files (default: the store's sync_dir)")
+	syncCmd.Flags().BoolVar(&syncFromFiles, "from-files", false, "Only apply the files to the store")
+	syncCmd.Flags().BoolVar(&syncToFiles, "to-files", false, "Only write the store to the files")
+	syncCmd.Flags().BoolVar(&syncRebuild, "rebuild", false, "Make the store match the files exactly and rebuild its indexes")
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	if syncFromFiles && syncToFiles {
+		return fmt.Errorf("--from-files and --to-files are mutually exclusive")
+	}
+	if syncRebuild && syncToFiles {
+		return fmt.Errorf("--rebuild reads the files and cannot be used with --to-files")
+	}
+
+	engine, err := getEngine()
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	opts := core.SyncOptions{
+		Store:   syncStore,
+		Dir:     syncDir,
+		Rebuild: syncRebuild,
+	}
+
+	var stats core.SyncStats
+	switch {
+	case syncFromFiles:
+		stats, err = engine.SyncFromFiles(context.Background(), opts)
+	case syncToFiles:
+		stats, err = engine.SyncToFiles(opts)
+	default:
+		stats, err = engine.Sync(context.Background(), opts)
+	}
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+
+	if verbose {
+		printJSON(stats)
+		return nil
+	}
+
+	fmt.Printf("✓ Synced %s\n", stats.Dir)
+	if !syncToFiles {
+		fmt.Printf("  From files: %d created, %d updated, %d deleted, %d relations changed\n",
+			stats.Created, stats.Updated, stats.Deleted, stats.Relations)
+		if stats.Kept > 0 {
+			fmt.Printf("  Kept:       %d local changes not yet in the files\n", stats.Kept)
+		}
+		if stats.Conflicts > 0 {
+			fmt.Printf("  Conflicts:  %d (changed on both sides, newer version kept)\n", stats.Conflicts)
+		}
+		fmt.Printf("  Embedded:   %d\n", stats.Embedded)
+	}
+	if !syncFromFiles {
+		fmt.Printf("  To files:   %d written, %d removed\n", stats.Written, stats.Removed)
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Default clustering parameters
+const (
+	DefaultClusterThreshold = 0.85
+	DefaultClusterNeighbors = 10
+)
+
+// Share of a pair's similarity that comes from their embeddings; the rest
+// comes from the overlap of their words
+const embeddingShare = 0.75
+
+// activeTrust lists the trust levels of memories that may still be merged.
+// Obsolete memories are left alone: merges mark the memories they replace
+// as obsolete.
+var activeTrust = []types.TrustLevel{
+	types.TrustProposed,
+	types.TrustValidated,
+	types.TrustProven,
+	types.TrustDisputed,
+}
+
+// ClusterOptions configures a search for groups of duplicate memories
+type ClusterOptions struct {
+	Store     string  // Store to search (default: the default store)
+	Threshold float64 // Similarity at which two memories are duplicates, 0-1 (default: 0.85)
+	Neighbors int     // Candidates compared with each memory (default: 10)
+}
+
+// Cluster is a group of memories that duplicate each other
+type Cluster struct {
+	Memories   []*types.Memory `json:"memories"`   // Suggested survivor first
+	Similarity float64         `json:"similarity"` // Lowest similarity of the pairs that joined the group
+}
+
+// FindClusters groups a store's memories that duplicate each other. Each
+// memory is compared with its nearest neighbors by embedding and by full-text
+// search; a pair is a duplicate if its similarity, mostly cosine similarity of
+// the embeddings plus the overlap of their words, reaches the threshold.
+// Clusters are returned largest first.
+func (e *Engine) FindClusters(opts ClusterOptions) ([]Cluster, error) {
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultClusterThreshold
+	}
+	if opts.Threshold < 0 || opts.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if opts.Neighbors <= 0 {
+		opts.Neighbors = DefaultClusterNeighbors
+	}
+
+	l, err := e.writableLayer(opts.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	memories, err := l.db.ListMemories(types.RecallOptions{TrustLevels: activeTrust})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	byID := make(map[string]*types.Memory, len(memories))
+	words := make(map[string][]string, len(memories))
+	embs := make(map[string][]float32, len(memories))
+	for _, m := range memories {
+		m.Store = l.name
+		byID[m.ID] = m
+		words[m.ID] = uniqueWords(m.Content)
+		if emb, err := l.db.GetEmbedding(m.ID); err == nil && emb != nil {
+			embs[m.ID] = emb
+		}
+	}
+
+	// Union-find over duplicate pairs
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	weakest := make(map[string]float64) // Per root: lowest joining similarity
+
+	filter := types.RecallOptions{TrustLevels: activeTrust}
+	for _, m := range memories {
+		neighbors := make(map[string]bool)
+		if emb := embs[m.ID]; emb != nil {
+			results, err := l.db.VectorSearch(emb, opts.Neighbors+1, filter)
+			if err != nil {
+				return nil, fmt.Errorf("vector search failed: %w", err)
+			}
+			for _, r := range results {
+				neighbors[r.MemoryID] = true
+			}
+		}
+		if query := db.FreeTextQuery(m.Content); query != "" {
+			results, err := l.db.FTSSearch(query, opts.Neighbors+1, filter)
+			if err != nil {
+				return nil, fmt.Errorf("full-text search failed: %w", err)
+			}
+			for _, r := range results {
+				neighbors[r.MemoryID] = true
+			}
+		}
+
+		for id := range neighbors {
+			if id <= m.ID || byID[id] == nil {
+				continue // Each pair once
+			}
+			sim := pairSimilarity(embs[m.ID], embs[id], words[m.ID], words[id])
+			if sim < opts.Threshold {
+				continue
+			}
+
+			a, b := find(m.ID), find(id)
+			low := sim
+			for _, root := range []string{a, b} {
+				if w, ok := weakest[root]; ok && w < low {
+					low = w
+				}
+			}
+			if a != b {
+				parent[b] = a
+				delete(weakest, b)
+			}
+			weakest[a] = low
+		}
+	}
+
+	groups := make(map[string][]*types.Memory)
+	for _, m := range memories {
+		if _, ok := weakest[find(m.ID)]; ok {
+			root := find(m.ID)
+			groups[root] = append(groups[root], m)
+		}
+	}
+
+	var clusters []Cluster
+	for root, members := range groups {
+		sort.SliceStable(members, func(i, j int) bool {
+			return survivorBefore(members[i], members[j])
+		})
+		clusters = append(clusters, Cluster{Memories: members, Similarity: weakest[root]})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Memories) != len(clusters[j].Memories) {
+			return len(clusters[i].Memories) > len(clusters[j].Memories)
+		}
+		return clusters[i].Similarity > clusters[j].Similarity
+	})
+
+	return clusters, nil
+}
+
+// Merge folds duplicates into a surviving memory. The survivor gains their
+// tags, the highest trust among them and the sum of their access counts, and
+// their relations are moved to it. Each duplicate is kept as an obsolete
+// memory that the survivor `replaces`.
+func (e *Engine) Merge(survivorID string, duplicateIDs []string) (*types.Memory, error) {
+	l, survivor, err := e.locateWritable(survivorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []*types.Memory
+	for _, id := range duplicateIDs {
+		if id == survivorID {
+			continue
+		}
+		dl, dup, err := e.locateWritable(id)
+		if err != nil {
+			return nil, err
+		}
+		if dl != l {
+			return nil, fmt.Errorf("memory %s is in store %s, not %s", id, dl.name, l.name)
+		}
+		duplicates = append(duplicates, dup)
+	}
+
+	for _, dup := range duplicates {
+		survivor.Tags = mergeTags(survivor.Tags, dup.Tags)
+		if trustRank(dup.Trust) > trustRank(survivor.Trust) {
+			survivor.Trust = dup.Trust
+		}
+		survivor.AccessCnt += dup.AccessCnt
+	}
+	survivor.UpdatedAt = timeNow()
+
+	replaces := make([]*types.Relation, len(duplicates))
+	for i, dup := range duplicates {
+		replaces[i] = &types.Relation{
+			ID:        generateID(),
+			FromID:    survivor.ID,
+			ToID:      dup.ID,
+			Type:      types.RelReplaces,
+			Note:      "merged duplicate",
+			CreatedAt: timeNow(),
+		}
+	}
+
+	// All or nothing, so a failure can't leave duplicates half retired
+	if err := l.db.MergeMemories(survivor, replaces); err != nil {
+		return nil, err
+	}
+
+	survivor.Store = l.name
+	return survivor, nil
+}
+
+// writableLayer returns the named store, or the default store, if it can be written
+func (e *Engine) writableLayer(name string) (*layer, error) {
+	if name == "" {
+		for _, l := range e.layers {
+			if l.db == e.db {
+				return l, nil
+			}
+		}
+	}
+	l, err := e.layer(name)
+	if err != nil {
+		return nil, err
+	}
+	if l.readOnly {
+		return nil, fmt.Errorf("store %s is read-only", l.name)
+	}
+	return l, nil
+}
+
+// pairSimilarity scores how much two memories duplicate each other, 0-1
+func pairSimilarity(embA, embB []float32, wordsA, wordsB []string) float64 {
+	var semantic float64
+	if embA != nil && embB != nil {
+		semantic = cosine(l2Distance(embA, embB))
+	}
+	return embeddingShare*semantic + (1-embeddingShare)*wordOverlap(wordsA, wordsB)
+}
+
+// wordOverlap returns the Jaccard index of two word sets
+func wordOverlap(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, w := range a {
+		set[w] = true
+	}
+	shared := 0
+	for _, w := range b {
+		if set[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// survivorBefore orders the members of a cluster by how well they would
+// survive a merge: highest trust, then most used, then oldest
+func survivorBefore(a, b *types.Memory) bool {
+	if ra, rb := trustRank(a.Trust), trustRank(b.Trust); ra != rb {
+		return ra > rb
+	}
+	if a.AccessCnt != b.AccessCnt {
+		return a.AccessCnt > b.AccessCnt
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// trustRank orders trust levels from obsolete to proven
+func trustRank(t types.TrustLevel) int {
+	switch t {
+	case types.TrustProven:
+		return 4
+	case types.TrustValidated:
+		return 3
+	case types.TrustProposed:
+		return 2
+	case types.TrustDisputed:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestFindClusters(t *testing.T) {
+	// Memories with the same words, so that their similarity is
+	// 0.75*cosine + 0.25. Deploy x and y, and y and z, are 30 degrees apart
+	// (similarity 0.90); x and z are 60 degrees apart (0.625).
+	memories := []struct {
+		name    string
+		content string
+		vector  []float32
+		trust   types.TrustLevel
+	}{
+		{"x", "deploy with make release", []float32{1, 0}, types.TrustProposed},
+		{"y", "Deploy with make release.", []float32{0.866, 0.5}, types.TrustProposed},
+		{"z", "deploy: with make release", []float32{0.5, 0.866}, types.TrustValidated},
+		{"tabs", "tabs over spaces", []float32{0, 0, 1}, types.TrustProposed},
+		{"tabs-copy", "Tabs over spaces.", []float32{0, 0, 1}, types.TrustProven},
+		{"tabs-old", "tabs over spaces!", []float32{0, 0, 1}, types.TrustObsolete},
+		{"other", "the build needs go 1.22", []float32{0, 0, 0, 1}, types.TrustProposed},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      string // Clusters, largest first, survivor first
+		weakest   []float64
+	}{
+		{
+			name:    "duplicates of duplicates join a cluster",
+			want:    "z,x,y tabs-copy,tabs",
+			weakest: []float64{0.75*0.866 + 0.25, 1},
+		},
+		{
+			name:      "higher threshold",
+			threshold: 0.95,
+			want:      "tabs-copy,tabs",
+			weakest:   []float64{1},
+		},
+		{
+			name:      "lower threshold",
+			threshold: 0.6,
+			want:      "z,x,y tabs-copy,tabs",
+			weakest:   []float64{0.75*0.5 + 0.25, 1},
+		},
+	}
+
+	embedder := newFakeEmbedder()
+	e := newTestEngine(t, embedder, func(cfg *types.Config) {
+		cfg.Dedupe = &types.DedupeConfig{Action: DedupeOff}
+	})
+	names := make(map[string]string)
+	for _, m := range memories {
+		embedder.set(m.content, m.vector...)
+		stored := store(t, e, m.content, types.StoreOptions{Trust: m.trust}).Memory
+		names[stored.ID] = m.name
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters, err := e.FindClusters(ClusterOptions{Threshold: tt.threshold})
+			if err != nil {
+				t.Fatalf("FindClusters: %v", err)
+			}
+
+			var got []string
+			for i, c := range clusters {
+				var members []string
+				for _, m := range c.Memories {
+					members = append(members, names[m.ID])
+				}
+				got = append(got, strings.Join(members, ","))
+				if i < len(tt.weakest) && math.Abs(c.Similarity-tt.weakest[i]) > 0.01 {
+					t.Errorf("cluster %d similarity = %.3f, want %.3f", i, c.Similarity, tt.weakest[i])
+				}
+			}
+			if s := strings.Join(got, " "); s != tt.want {
+				t.Errorf("clusters = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	embedder := newFakeEmbedder()
+	e := newTestEngine(t, embedder, func(cfg *types.Config) {
+		cfg.Dedupe = &types.DedupeConfig{Action: DedupeOff}
+	})
+
+	add := func(content string, tags []string, trust types.TrustLevel, accesses int, vector ...float32) *types.Memory {
+		embedder.set(content, vector...)
+		m := store(t, e, content, types.StoreOptions{Tags: tags, Trust: trust}).Memory
+		for i := 0; i < accesses; i++ {
+			if err := e.db.IncrementAccessCount(m.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return m
+	}
+	survivor := add("sqlite holds the memories", []string{"db"}, types.TrustProposed, 2, 1)
+	dup1 := add("the memories live in sqlite", []string{"storage"}, types.TrustProven, 3, 1, 0.1)
+	dup2 := add("sqlite stores every memory", []string{"db", "local"}, types.TrustValidated, 1, 1, 0.2)
+	cgo := add("install a cgo toolchain", nil, types.TrustProposed, 0, 0, 1)
+	locked := add("database is locked errors", nil, types.TrustProposed, 0, 0, 0, 1)
+
+	relate := func(from, to *types.Memory, relType types.RelationType) {
+		if _, err := e.Relate(from.ID, to.ID, relType, ""); err != nil {
+			t.Fatalf("Relate: %v", err)
+		}
+	}
+	relate(dup1, cgo, types.RelRequires)
+	relate(survivor, cgo, types.RelRequires) // Same as the moved one
+	relate(dup2, locked, types.RelCauses)
+	relate(dup1, survivor, types.RelRelatedTo) // Would relate the survivor to itself
+
+	merged, err := e.Merge(survivor.ID, []string{dup1.ID, dup2.ID, survivor.ID})
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	// The survivor takes the tags, the highest trust and the accesses
+	stored, err := e.db.GetMemory(survivor.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*types.Memory{merged, stored} {
+		if got := strings.Join(m.Tags, ","); got != "db,storage,local" {
+			t.Errorf("tags = %s, want db,storage,local", got)
+		}
+		if m.Trust != types.TrustProven || m.AccessCnt != 6 {
+			t.Errorf("trust %s with %d accesses, want proven with 6", m.Trust, m.AccessCnt)
+		}
+	}
+
+	// Relations move to the survivor, without duplicates or self-relations,
+	// and the survivor replaces each duplicate
+	relations, err := e.db.ListRelations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{survivor.ID: "survivor", dup1.ID: "dup1", dup2.ID: "dup2", cgo.ID: "cgo", locked.ID: "locked"}
+	var got []string
+	for _, r := range relations {
+		got = append(got, names[r.FromID]+" "+string(r.Type)+" "+names[r.ToID])
+	}
+	sort.Strings(got)
+	want := []string{
+		"survivor causes locked",
+		"survivor replaces dup1",
+		"survivor replaces dup2",
+		"survivor requires cgo",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("relations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The duplicates are kept for the replaces relations, but retired: they
+	// are obsolete, and so are their vector and full-text matches
+	for _, dup := range []*types.Memory{dup1, dup2} {
+		m, err := e.db.GetMemory(dup.ID)
+		if err != nil || m == nil {
+			t.Fatalf("GetMemory(%s): %v, %v", names[dup.ID], m, err)
+		}
+		if m.Trust != types.TrustObsolete || m.Content != dup.Content {
+			t.Errorf("%s is %s with content %q, want obsolete and unchanged", names[dup.ID], m.Trust, m.Content)
+		}
+	}
+
+	vector, _ := embedder.Embed(context.Background(), dup1.Content)
+	for _, tt := range []struct {
+		trust []types.TrustLevel
+		want  string
+	}{
+		{activeTrust, "survivor"},
+		{[]types.TrustLevel{types.TrustObsolete}, "dup1,dup2"},
+	} {
+		filter := types.RecallOptions{TrustLevels: tt.trust}
+
+		nearest, err := e.db.VectorSearch(vector, 3, filter)
+		if err != nil {
+			t.Fatalf("VectorSearch: %v", err)
+		}
+		var found []string
+		for _, r := range nearest {
+			if r.Distance < 0.5 {
+				found = append(found, names[r.MemoryID])
+			}
+		}
+		sort.Strings(found)
+		if s := strings.Join(found, ","); s != tt.want {
+			t.Errorf("vector search among %v found %s, want %s", tt.trust, s, tt.want)
+		}
+
+		matches, err := e.db.FTSSearch(db.FreeTextQuery("sqlite"), 10, filter)
+		if err != nil {
+			t.Fatalf("FTSSearch: %v", err)
+		}
+		found = nil
+		for _, r := range matches {
+			found = append(found, names[r.MemoryID])
+		}
+		sort.Strings(found)
+		if s := strings.Join(found, ","); s != tt.want {
+			t.Errorf("full-text search among %v found %s, want %s", tt.trust, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Near-duplicate actions
+const (
+	DedupeMerge  = "merge"  // Bump the existing memory instead of storing
+	DedupeRefuse = "refuse" // Don't store
+	DedupeRevise = "revise" // Store as a new revision of the existing memory
+	DedupeRelate = "relate" // Store and relate to the existing memory
+	DedupeOff    = "off"    // Don't check
+)
+
+// DefaultDedupeThreshold is the cosine similarity at which content counts as a duplicate
+const DefaultDedupeThreshold = 0.95
+
+// resolveDedupe fills in defaults for the near-duplicate check
+func resolveDedupe(cfg *types.DedupeConfig) (types.DedupeConfig, error) {
+	var d types.DedupeConfig
+	if cfg != nil {
+		d = *cfg
+	}
+
+	switch d.Action {
+	case "":
+		// Only link duplicates unless configured otherwise: the other actions
+		// drop or rewrite content the caller asked to store
+		d.Action = DedupeRelate
+	case DedupeMerge, DedupeRefuse, DedupeRevise, DedupeRelate, DedupeOff:
+	default:
+		return d, fmt.Errorf("unknown dedupe action: %s", d.Action)
+	}
+	if d.Threshold < 0 || d.Threshold > 1 {
+		return d, fmt.Errorf("dedupe threshold must be between 0 and 1")
+	}
+	if d.Threshold == 0 {
+		d.Threshold = DefaultDedupeThreshold
+	}
+
+	return d, nil
+}
+
+// findDuplicate returns the default store's memory closest to an embedding
+// if it is at least as similar as the threshold. Obsolete memories are skipped.
+func (e *Engine) findDuplicate(embedding []float32) (*types.Memory, float64, error) {
+	nearest, err := e.db.VectorSearch(embedding, 1, types.RecallOptions{TrustLevels: activeTrust})
+	if err != nil {
+		return nil, 0, fmt.Errorf("duplicate check failed: %w", err)
+	}
+	if len(nearest) == 0 {
+		return nil, 0, nil
+	}
+
+	sim := cosine(nearest[0].Distance)
+	if sim < e.dedupe.Threshold {
+		return nil, 0, nil
+	}
+
+	memory, err := e.db.GetMemory(nearest[0].MemoryID)
+	if err != nil || memory == nil {
+		return nil, 0, err
+	}
+	return memory, sim, nil
+}
+
+// absorbable reports whether content stored with opts may be folded into a
+// near-duplicate. Content for another topic key or project is stored on its
+// own (and related) even if it reads the same.
+func absorbable(opts types.StoreOptions, dup *types.Memory) bool {
+	if opts.TopicKey != "" && dup.TopicKey != "" && opts.TopicKey != dup.TopicKey {
+		return false
+	}
+	if opts.Project != "" && dup.Metadata.Project != "" && opts.Project != dup.Metadata.Project {
+		return false
+	}
+	return true
+}
+
+// absorbDuplicate applies the merge, refuse or revise action to content that
+// duplicates an existing memory. What the caller gave that the duplicate lacks
+// (topic key, project, source, type) is carried over.
+func (e *Engine) absorbDuplicate(content string, opts types.StoreOptions, dup *types.Memory, sim float64, embedding []float32) (*types.StoreResult, error) {
+	result := &types.StoreResult{Duplicate: dup, Similarity: sim}
+
+	switch e.dedupe.Action {
+	case DedupeRefuse:
+		result.Action = types.StoreRefused
+		return result, nil
+
+	case DedupeRevise:
+		if err := e.ensureBaseRevision(e.db, dup); err != nil {
+			return nil, fmt.Errorf("failed to record revision: %w", err)
+		}
+		dup.Content = content
+		if opts.Type != "" {
+			dup.Type = opts.Type
+		}
+		result.Action = types.StoreRevised
+
+	default:
+		// Seen again: count it as a use
+		dup.AccessCnt++
+		if opts.Type != "" && dup.Type == types.TypeGeneral {
+			dup.Type = opts.Type
+		}
+		result.Action = types.StoreMerged
+	}
+
+	if dup.TopicKey == "" {
+		dup.TopicKey = opts.TopicKey
+	}
+	if dup.Metadata.Project == "" {
+		dup.Metadata.Project = opts.Project
+	}
+	if dup.Metadata.Source == "" {
+		dup.Metadata.Source = opts.Source
+	}
+	for k, v := range opts.ExtraData {
+		if _, ok := dup.Metadata.ExtraData[k]; !ok {
+			if dup.Metadata.ExtraData == nil {
+				dup.Metadata.ExtraData = make(map[string]string)
+			}
+			dup.Metadata.ExtraData[k] = v
+		}
+	}
+	dup.Tags = mergeTags(dup.Tags, opts.Tags)
+	dup.UpdatedAt = timeNow()
+
+	if err := e.db.SaveMemory(dup); err != nil {
+		return nil, fmt.Errorf("failed to save memory: %w", err)
+	}
+
+	if result.Action == types.StoreRevised {
+		if err := e.saveRevision(e.db, dup, opts.Source); err != nil {
+			return nil, fmt.Errorf("failed to record revision: %w", err)
+		}
+		if err := e.db.SaveEmbedding(dup.ID, embedding, e.embedder.Model()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to save embedding (queued for retry): %v\n", err)
+			e.queueEmbedding(e.db, dup.ID, err)
+		}
+	}
+
+	result.Memory = dup
+	return result, nil
+}
+
+// relateDuplicate links a newly stored memory to the near-duplicate it resembles
+func (e *Engine) relateDuplicate(memory, dup *types.Memory, sim float64) error {
+	return e.db.SaveRelation(&types.Relation{
+		ID:        generateID(),
+		FromID:    memory.ID,
+		ToID:      dup.ID,
+		Type:      types.RelRelatedTo,
+		Note:      fmt.Sprintf("near-duplicate (similarity %.2f)", sim),
+		CreatedAt: timeNow(),
+	})
+}
+
+// mergeTags appends the tags of b missing from a
+func mergeTags(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, t := range a {
+		seen[t] = true
+	}
+	for _, t := range b {
+		if !seen[t] {
+			seen[t] = true
+			a = append(a, t)
+		}
+	}
+	return a
+}
+
+// cosine converts an L2 distance between unit vectors to cosine similarity
+func cosine(distance float64) float64 {
+	return 1 - distance*distance/2
+}
+
+// embedForDedupe embeds content for the duplicate check. It returns nil
+// without an error if the check is off; on an error Store skips the check
+// and queues the embedding rather than trying again.
+func (e *Engine) embedForDedupe(ctx context.Context, content string, opts types.StoreOptions) ([]float32, error) {
+	if opts.AllowDuplicate || e.dedupe.Action == DedupeOff {
+		return nil, nil
+	}
+	return e.embedContent(ctx, e.db, content)
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestStoreDedupe(t *testing.T) {
+	const existingContent, newContent = "we use sqlite for storage", "storage is sqlite"
+
+	tests := []struct {
+		name     string
+		dedupe   *types.DedupeConfig
+		existing types.StoreOptions // Options the existing memory was stored with
+		obsolete bool               // Retire the existing memory first
+		vector   []float32          // Embedding of the new content; the existing memory's is (1, 0)
+		opts     types.StoreOptions
+		want     types.StoreAction
+		memories int // Memories in the store afterwards
+		check    func(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult)
+	}{
+		{
+			name:     "below the threshold",
+			vector:   []float32{1, 0.5}, // Similarity 0.89
+			want:     types.StoreCreated,
+			memories: 2,
+			check:    expectNoRelation,
+		},
+		{
+			name:     "related by default",
+			vector:   []float32{1, 0.1}, // Similarity 0.995
+			want:     types.StoreRelated,
+			memories: 2,
+			check:    expectRelated,
+		},
+		{
+			name:     "configured threshold",
+			dedupe:   &types.DedupeConfig{Threshold: 0.85},
+			vector:   []float32{1, 0.5},
+			want:     types.StoreRelated,
+			memories: 2,
+			check:    expectRelated,
+		},
+		{
+			name:     "refused",
+			dedupe:   &types.DedupeConfig{Action: DedupeRefuse},
+			vector:   []float32{1, 0.1},
+			want:     types.StoreRefused,
+			memories: 1,
+			check: func(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult) {
+				if result.Memory != nil {
+					t.Errorf("refused content returned memory %+v", result.Memory)
+				}
+				if m, _ := e.db.GetMemory(existing.ID); m.Content != existingContent || m.AccessCnt != 0 {
+					t.Errorf("duplicate changed by a refusal: %+v", m)
+				}
+			},
+		},
+		{
+			name:     "merged",
+			dedupe:   &types.DedupeConfig{Action: DedupeMerge},
+			existing: types.StoreOptions{Tags: []string{"db"}},
+			vector:   []float32{1, 0.1},
+			opts:     types.StoreOptions{Tags: []string{"sqlite"}, Type: types.TypeDecision, TopicKey: "db/engine", Project: "cortex"},
+			want:     types.StoreMerged,
+			memories: 1,
+			check: func(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult) {
+				m, _ := e.db.GetMemory(existing.ID)
+				if m.Content != existingContent || m.AccessCnt != 1 {
+					t.Errorf("merged memory has content %q and %d accesses, want the old content and 1", m.Content, m.AccessCnt)
+				}
+				if !reflect.DeepEqual(m.Tags, []string{"db", "sqlite"}) || m.Type != types.TypeDecision ||
+					m.TopicKey != "db/engine" || m.Metadata.Project != "cortex" {
+					t.Errorf("merged memory didn't take the new tags, type, topic key and project: %+v", m)
+				}
+			},
+		},
+		{
+			name:     "revised",
+			dedupe:   &types.DedupeConfig{Action: DedupeRevise},
+			vector:   []float32{1, 0.1},
+			want:     types.StoreRevised,
+			memories: 1,
+			check: func(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult) {
+				if m, _ := e.db.GetMemory(existing.ID); m.Content != newContent {
+					t.Errorf("revised content = %q, want %q", m.Content, newContent)
+				}
+				revisions, err := e.db.GetRevisions(existing.ID)
+				if err != nil || len(revisions) != 2 {
+					t.Errorf("revisions = %d, %v, want the old and the new content", len(revisions), err)
+				}
+			},
+		},
+		{
+			name:     "merge of another topic key is related instead",
+			dedupe:   &types.DedupeConfig{Action: DedupeMerge},
+			existing: types.StoreOptions{TopicKey: "db/engine"},
+			vector:   []float32{1, 0.1},
+			opts:     types.StoreOptions{TopicKey: "db/cache"},
+			want:     types.StoreRelated,
+			memories: 2,
+			check:    expectRelated,
+		},
+		{
+			name:     "merge of another project is related instead",
+			dedupe:   &types.DedupeConfig{Action: DedupeMerge},
+			existing: types.StoreOptions{Project: "cortex"},
+			vector:   []float32{1, 0.1},
+			opts:     types.StoreOptions{Project: "other"},
+			want:     types.StoreRelated,
+			memories: 2,
+			check:    expectRelated,
+		},
+		{
+			name:     "duplicate allowed by the caller",
+			dedupe:   &types.DedupeConfig{Action: DedupeRefuse},
+			vector:   []float32{1, 0.1},
+			opts:     types.StoreOptions{AllowDuplicate: true},
+			want:     types.StoreCreated,
+			memories: 2,
+			check:    expectNoRelation,
+		},
+		{
+			name:     "check turned off",
+			dedupe:   &types.DedupeConfig{Action: DedupeOff},
+			vector:   []float32{1},
+			want:     types.StoreCreated,
+			memories: 2,
+			check:    expectNoRelation,
+		},
+		{
+			name:     "obsolete memories are not duplicates",
+			dedupe:   &types.DedupeConfig{Action: DedupeRefuse},
+			obsolete: true,
+			vector:   []float32{1},
+			want:     types.StoreCreated,
+			memories: 2,
+			check:    expectNoRelation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embedder := newFakeEmbedder()
+			e := newTestEngine(t, embedder, func(cfg *types.Config) { cfg.Dedupe = tt.dedupe })
+
+			embedder.set(existingContent, 1)
+			embedder.set(newContent, tt.vector...)
+			existing := store(t, e, existingContent, tt.existing).Memory
+			if tt.obsolete {
+				if err := e.db.UpdateTrust(existing.ID, types.TrustObsolete); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := store(t, e, newContent, tt.opts)
+			if result.Action != tt.want {
+				t.Fatalf("action = %s, want %s", result.Action, tt.want)
+			}
+			if tt.want != types.StoreCreated && (result.Duplicate == nil || result.Duplicate.ID != existing.ID) {
+				t.Errorf("duplicate = %+v, want the existing memory", result.Duplicate)
+			}
+
+			memories, err := e.db.ListMemories(types.RecallOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(memories) != tt.memories {
+				t.Errorf("store holds %d memories, want %d", len(memories), tt.memories)
+			}
+
+			tt.check(t, e, existing, result)
+		})
+	}
+}
+
+// expectRelated checks that the new memory is related to the existing one
+func expectRelated(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult) {
+	t.Helper()
+	relations, err := e.db.GetRelationsFrom(result.Memory.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relations) != 1 || relations[0].ToID != existing.ID || relations[0].Type != types.RelRelatedTo {
+		t.Errorf("relations of the new memory = %+v, want one related_to the existing memory", relations)
+	}
+}
+
+// expectNoRelation checks that the new memory is stored on its own
+func expectNoRelation(t *testing.T, e *Engine, existing *types.Memory, result *types.StoreResult) {
+	t.Helper()
+	relations, err := e.db.GetRelationsFrom(result.Memory.ID)
+	if err != nil || len(relations) != 0 {
+		t.Errorf("relations of the new memory = %+v, %v, want none", relations, err)
+	}
+}
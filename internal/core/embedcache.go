@@ -0,0 +1,113 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/constantino-dev/cortex/internal/db"
+)
+
+// queryCacheSize is how many query embeddings an engine keeps in memory
+const queryCacheSize = 256
+
+// embeddingCacheSize is how many content embeddings a store keeps cached
+const embeddingCacheSize = 10000
+
+// queryCache is a least-recently-used cache of query embeddings
+type queryCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Front: most recently used
+	items map[string]*list.Element
+}
+
+type queryEntry struct {
+	key       string
+	embedding []float32
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached embedding for key, marking it as recently used
+func (c *queryCache) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*queryEntry).embedding, true
+}
+
+// put caches an embedding, evicting the least recently used one when full
+func (c *queryCache) put(key string, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*queryEntry).embedding = embedding
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&queryEntry{key: key, embedding: embedding})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*queryEntry).key)
+	}
+}
+
+// embedContent returns the embedding of memory content, from the store's
+// cache when the same text was embedded before by the same model
+func (e *Engine) embedContent(ctx context.Context, store *db.DB, content string) ([]float32, error) {
+	model := e.embedder.Model()
+	hash := hashBytes([]byte(content))
+
+	if embedding, err := store.GetCachedEmbedding(model, hash); err == nil && embedding != nil {
+		store.Count(db.CacheHits, 1)
+		return embedding, e.ensureVectorIndex(len(embedding))
+	}
+
+	embedding, err := e.embedder.Embed(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	store.Count(db.CacheMisses, 1)
+	if err := store.CacheEmbedding(model, hash, embedding); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to cache embedding: %v\n", err)
+	}
+	return embedding, e.ensureVectorIndex(len(embedding))
+}
+
+// embedQuery returns the embedding of a recall query, from memory when the
+// same query was asked recently
+func (e *Engine) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	key := e.embedder.Model() + "\x00" + query
+
+	if embedding, ok := e.queries.get(key); ok {
+		e.db.Count(db.QueryCacheHits, 1)
+		return embedding, nil
+	}
+
+	embedding, err := e.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.ensureVectorIndex(len(embedding)); err != nil {
+		return nil, err
+	}
+	e.db.Count(db.QueryCacheMisses, 1)
+	e.queries.put(key, embedding)
+	return embedding, nil
+}
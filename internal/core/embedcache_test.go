@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestQueryCache(t *testing.T) {
+	c := newQueryCache(2)
+	c.put("a", []float32{1})
+	c.put("b", []float32{2})
+
+	// Using a makes b the least recently used
+	if v, ok := c.get("a"); !ok || v[0] != 1 {
+		t.Fatalf("get(a) = %v, %v", v, ok)
+	}
+	c.put("c", []float32{3})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("b still cached after the cache filled up")
+	}
+	for key, want := range map[string]float32{"a": 1, "c": 3} {
+		if v, ok := c.get(key); !ok || v[0] != want {
+			t.Errorf("get(%s) = %v, %v, want %v", key, v, ok, want)
+		}
+	}
+
+	// Putting a cached key replaces it without evicting anything
+	c.put("c", []float32{4})
+	if v, ok := c.get("c"); !ok || v[0] != 4 {
+		t.Errorf("get(c) after replacing it = %v, %v", v, ok)
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("a evicted by replacing c")
+	}
+}
+
+func TestEmbeddingCache(t *testing.T) {
+	embedder, reference := newFakeEmbedder(), newFakeEmbedder()
+	e := newTestEngine(t, embedder, nil)
+	ctx := context.Background()
+
+	steps := []struct {
+		name    string
+		model   string
+		content string
+		calls   int // Provider calls so far
+	}{
+		{"first embedding", "fake", "tabs over spaces", 1},
+		{"same content is a hit", "fake", "tabs over spaces", 1},
+		{"other content is a miss", "fake", "spaces over tabs", 2},
+		{"same content with another model is a miss", "other", "tabs over spaces", 3},
+		{"each model keeps its own", "fake", "tabs over spaces", 3},
+		{"and the other model's too", "other", "tabs over spaces", 3},
+	}
+
+	for _, s := range steps {
+		embedder.model = s.model
+		want, _ := reference.Embed(ctx, s.content)
+		got, err := e.embedContent(ctx, e.db, s.content)
+		if err != nil {
+			t.Fatalf("%s: embedContent: %v", s.name, err)
+		}
+		if n := embedder.count(); n != s.calls {
+			t.Errorf("%s: provider called %d times, want %d", s.name, n, s.calls)
+		}
+		if len(got) != len(want) || got[0] != want[0] {
+			t.Errorf("%s: embedding = %v, want %v", s.name, got, want)
+		}
+	}
+
+	stats, err := e.db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[db.CacheHits] != 3 || stats[db.CacheMisses] != 3 {
+		t.Errorf("counted %d hits and %d misses, want 3 and 3", stats[db.CacheHits], stats[db.CacheMisses])
+	}
+
+	// Storing content embedded before costs no provider call
+	embedder.model = "fake"
+	store(t, e, "spaces over tabs", types.StoreOptions{})
+	if n := embedder.count(); n != 3 {
+		t.Errorf("provider called %d times after storing cached content, want 3", n)
+	}
+}
+
+func TestRecallQueryCache(t *testing.T) {
+	embedder := newFakeEmbedder()
+	e := newTestEngine(t, embedder, nil)
+	e.queries = newQueryCache(2)
+	ctx := context.Background()
+
+	steps := []struct {
+		query string
+		calls int // Provider calls so far
+	}{
+		{"tabs", 1},
+		{"tabs", 1},
+		{"spaces", 2},
+		{"tabs", 2},
+		{"indent", 3}, // Evicts spaces, the least recently used
+		{"tabs", 3},
+		{"spaces", 4},
+	}
+
+	for i, s := range steps {
+		if _, err := e.Recall(ctx, s.query, types.RecallOptions{}); err != nil {
+			t.Fatalf("Recall(%q): %v", s.query, err)
+		}
+		if n := embedder.count(); n != s.calls {
+			t.Errorf("step %d (%s): provider called %d times, want %d", i, s.query, n, s.calls)
+		}
+	}
+
+	stats, err := e.db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[db.QueryCacheHits] != 3 || stats[db.QueryCacheMisses] != 4 {
+		t.Errorf("counted %d hits and %d misses, want 3 and 4", stats[db.QueryCacheHits], stats[db.QueryCacheMisses])
+	}
+
+	// A query is cached per model
+	embedder.model = "other"
+	if _, err := e.Recall(ctx, "tabs", types.RecallOptions{}); err != nil {
+		t.Fatalf("Recall: %v", err)
+	}
+	if n := embedder.count(); n != 5 {
+		t.Errorf("provider called %d times for a query cached for another model, want 5", n)
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/constantino-dev/cortex/internal/db"
 	"github.com/constantino-dev/cortex/internal/embeddings"
@@ -14,65 +15,226 @@ import (
 
 // Engine is the main Cortex engine that coordinates all services
 type Engine struct {
-	db       *db.DB
+	db       *db.DB   // Default store, which receives new memories
+	layers   []*layer // All stores, in recall order
 	embedder embeddings.Provider
 	config   *types.Config
+	ranking  types.RankingConfig
+	scoring  types.ScoringConfig
+	dedupe   types.DedupeConfig
+	reranker Reranker
+	queries  *queryCache
+
+	indexMu      sync.Mutex
+	indexPending bool // Vector indexes wait for the provider's dimension
 }
 
 // New creates a new Cortex engine
 func New(cfg *types.Config) (*Engine, error) {
-	// Ensure data directory exists
-	dir := filepath.Dir(cfg.DBPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	return open(cfg, nil, true)
+}
+
+// NewForReembed creates an engine without verifying that the vector index
+// matches the configured provider, so that Reembed can rebuild it
+func NewForReembed(cfg *types.Config) (*Engine, error) {
+	return open(cfg, nil, false)
+}
+
+// open creates an engine that embeds with embedder, or with the configured
+// provider if embedder is nil
+func open(cfg *types.Config, embedder embeddings.Provider, checkIndex bool) (*Engine, error) {
+	if embedder == nil {
+		switch cfg.EmbeddingProvider {
+		case "openai", "":
+			if cfg.OpenAIKey == "" {
+				return nil, fmt.Errorf("OpenAI API key required")
+			}
+		case "ollama":
+		default:
+			return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+		}
+	}
+
+	ranking, err := resolveRanking(cfg.Ranking)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ranking config: %w", err)
+	}
+
+	scoring, err := resolveScoring(cfg.Scoring)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scoring config: %w", err)
 	}
 
-	// Initialize database
-	database, err := db.New(cfg.DBPath)
+	dedupe, err := resolveDedupe(cfg.Dedupe)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+		return nil, fmt.Errorf("invalid dedupe config: %w", err)
 	}
 
-	// Initialize embedding provider
-	var embedder embeddings.Provider
-	switch cfg.EmbeddingProvider {
-	case "openai", "":
-		if cfg.OpenAIKey == "" {
-			database.Close()
-			return nil, fmt.Errorf("OpenAI API key required")
+	reranker, err := newReranker(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rerank config: %w", err)
+	}
+
+	// Initialize databases
+	layers, err := openLayers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if embedder == nil {
+		embedder = newEmbedder(cfg, layers)
+	}
+
+	// The vector index is sized for the provider; refuse a mismatched one.
+	// A provider that doesn't know its dimension yet gets its missing indexes
+	// created on its first embedding.
+	if checkIndex {
+		for _, l := range layers {
+			if err := l.initVectorIndex(embedder.Model(), embedder.Dimensions()); err != nil {
+				closeLayers(layers)
+				return nil, fmt.Errorf("failed to initialize vector index of store %s: %w (run 'cortex reembed' to rebuild it)", l.name, err)
+			}
 		}
-		embedder = embeddings.NewOpenAI(cfg.OpenAIKey)
-	case "ollama":
-		// TODO: implement Ollama provider
-		database.Close()
-		return nil, fmt.Errorf("ollama provider not yet implemented")
-	default:
-		database.Close()
-		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
 	}
 
-	return &Engine{
-		db:       database,
+	e := &Engine{
+		layers:   layers,
 		embedder: embedder,
 		config:   cfg,
-	}, nil
+		ranking:  ranking,
+		scoring:  scoring,
+		dedupe:   dedupe,
+		reranker: reranker,
+		queries:  newQueryCache(queryCacheSize),
+
+		indexPending: checkIndex && embedder.Dimensions() == 0,
+	}
+
+	defaultStore := cfg.DefaultStore
+	if defaultStore == "" {
+		defaultStore = ProjectStore
+	}
+	primary, err := e.layer(defaultStore)
+	if err != nil {
+		closeLayers(layers)
+		return nil, fmt.Errorf("invalid default store: %w", err)
+	}
+	if primary.readOnly {
+		closeLayers(layers)
+		return nil, fmt.Errorf("default store %s is read-only", primary.name)
+	}
+	e.db = primary.db
+
+	return e, nil
+}
+
+// newEmbedder creates the configured embedding provider. Ollama is told the
+// dimension of its model if a store was already built with it, so that it
+// isn't probed by commands that embed nothing.
+func newEmbedder(cfg *types.Config, layers []*layer) embeddings.Provider {
+	if cfg.EmbeddingProvider != "ollama" {
+		return embeddings.NewOpenAI(cfg.OpenAIKey)
+	}
+
+	model := cfg.OllamaModel
+	if model == "" {
+		model = embeddings.DefaultOllamaModel
+	}
+	dimensions := 0
+	for _, l := range layers {
+		storedModel, storedDims, err := l.db.EmbeddingModel()
+		if err == nil && storedModel == model {
+			dimensions = storedDims
+			break
+		}
+	}
+	return embeddings.NewOllama(cfg.OllamaURL, model, dimensions)
+}
+
+// ensureVectorIndex creates the vector indexes that open left for later
+// because the provider's dimension wasn't known yet
+func (e *Engine) ensureVectorIndex(dimensions int) error {
+	e.indexMu.Lock()
+	defer e.indexMu.Unlock()
+
+	if !e.indexPending {
+		return nil
+	}
+	for _, l := range e.layers {
+		if err := l.initVectorIndex(e.embedder.Model(), dimensions); err != nil {
+			return fmt.Errorf("failed to initialize vector index of store %s: %w", l.name, err)
+		}
+	}
+	e.indexPending = false
+	return nil
+}
+
+// dimensions returns the provider's vector dimension, embedding a probe text
+// if the provider doesn't know it yet
+func (e *Engine) dimensions(ctx context.Context) (int, error) {
+	if dims := e.embedder.Dimensions(); dims > 0 {
+		return dims, nil
+	}
+	probe, err := e.embedder.Embed(ctx, "dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach embedding provider: %w", err)
+	}
+	return len(probe), nil
 }
 
 // Close shuts down the engine
 func (e *Engine) Close() error {
-	return e.db.Close()
+	for _, l := range e.layers {
+		if l.readOnly {
+			continue
+		}
+		if _, err := l.db.PruneEmbeddingCache(embeddingCacheSize); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to prune embedding cache of store %s: %v\n", l.name, err)
+		}
+	}
+	return closeLayers(e.layers)
 }
 
-// Store saves a new memory or updates an existing one (if TopicKey matches)
-func (e *Engine) Store(ctx context.Context, content string, opts types.StoreOptions) (*types.Memory, error) {
+// Store saves a new memory or updates an existing one (if TopicKey matches).
+// New content that nearly duplicates an existing memory is handled as the
+// dedupe config says; the result reports what was done.
+func (e *Engine) Store(ctx context.Context, content string, opts types.StoreOptions) (*types.StoreResult, error) {
 	// Check if we should update existing memory by topic key
 	var existing *types.Memory
 	if opts.TopicKey != "" {
 		existing, _ = e.db.GetMemoryByTopicKey(opts.TopicKey)
 	}
 
+	// Check new content against existing memories
+	var embedding []float32
+	var embedErr error
+	var dup *types.Memory
+	var sim float64
+	if existing == nil {
+		embedding, embedErr = e.embedForDedupe(ctx, content, opts)
+		if embedding != nil {
+			var err error
+			if dup, sim, err = e.findDuplicate(embedding); err != nil {
+				return nil, err
+			}
+			if dup != nil && e.dedupe.Action != DedupeRelate && absorbable(opts, dup) {
+				return e.absorbDuplicate(content, opts, dup, sim, embedding)
+			}
+		}
+	}
+
+	// Don't save if the caller gave up while the content was embedded
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var memory *types.Memory
 	if existing != nil {
+		// Keep the version being replaced if it predates revision history
+		if err := e.ensureBaseRevision(e.db, existing); err != nil {
+			return nil, fmt.Errorf("failed to record revision: %w", err)
+		}
+
 		// Update existing memory (topic key evolution)
 		memory = existing
 		memory.Content = content
@@ -99,8 +261,8 @@ func (e *Engine) Store(ctx context.Context, content string, opts types.StoreOpti
 			UpdatedAt: timeNow(),
 			AccessCnt: 0,
 			Metadata: types.Metadata{
-				Source:  opts.Source,
-				Project: opts.Project,
+				Source:    opts.Source,
+				Project:   opts.Project,
 				ExtraData: opts.ExtraData,
 			},
 		}
@@ -119,21 +281,59 @@ func (e *Engine) Store(ctx context.Context, content string, opts types.StoreOpti
 		return nil, fmt.Errorf("failed to save memory: %w", err)
 	}
 
-	// Generate and save embedding
-	embedding, err := e.embedder.Embed(ctx, content)
-	if err != nil {
-		// Log but don't fail - memory is still saved
-		fmt.Fprintf(os.Stderr, "warning: failed to generate embedding: %v\n", err)
-	} else {
+	// Topic-keyed memories keep a history of every version
+	if memory.TopicKey != "" {
+		if err := e.saveRevision(e.db, memory, opts.Source); err != nil {
+			return nil, fmt.Errorf("failed to record revision: %w", err)
+		}
+	}
+
+	if embedding != nil {
 		if err := e.db.SaveEmbedding(memory.ID, embedding, e.embedder.Model()); err != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to save embedding: %v\n", err)
+			fmt.Fprintf(os.Stderr, "warning: failed to save embedding (queued for retry): %v\n", err)
+			e.queueEmbedding(e.db, memory.ID, err)
 		}
+	} else if embedErr != nil {
+		// Already tried once for the duplicate check
+		fmt.Fprintf(os.Stderr, "warning: failed to generate embedding (queued for retry): %v\n", embedErr)
+		e.queueEmbedding(e.db, memory.ID, embedErr)
+	} else {
+		e.embed(ctx, e.db, memory)
 	}
 
-	return memory, nil
+	result := &types.StoreResult{Memory: memory, Action: types.StoreCreated}
+	if existing != nil {
+		result.Action = types.StoreUpdated
+	}
+	if dup != nil {
+		if err := e.relateDuplicate(memory, dup, sim); err != nil {
+			return nil, fmt.Errorf("failed to relate duplicate: %w", err)
+		}
+		result.Action = types.StoreRelated
+		result.Duplicate = dup
+		result.Similarity = sim
+	}
+
+	return result, nil
 }
 
-// Recall searches for relevant memories
+// embed generates and saves the embedding for a memory's content.
+// Failures are logged and queued for retry but not returned - the memory
+// itself is already saved.
+func (e *Engine) embed(ctx context.Context, store *db.DB, memory *types.Memory) {
+	embedding, err := e.embedContent(ctx, store, memory.Content)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to generate embedding (queued for retry): %v\n", err)
+		e.queueEmbedding(store, memory.ID, err)
+		return
+	}
+	if err := store.SaveEmbedding(memory.ID, embedding, e.embedder.Model()); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to save embedding (queued for retry): %v\n", err)
+		e.queueEmbedding(store, memory.ID, err)
+	}
+}
+
+// Recall searches all stores for relevant memories and merges the results
 func (e *Engine) Recall(ctx context.Context, query string, opts types.RecallOptions) ([]types.SearchResult, error) {
 	// Set defaults
 	if opts.Limit == 0 {
@@ -150,117 +350,275 @@ func (e *Engine) Recall(ctx context.Context, query string, opts types.RecallOpti
 		}
 	}
 
+	if opts.MMRLambda < 0 || opts.MMRLambda > 1 {
+		return nil, fmt.Errorf("MMR lambda must be between 0 and 1")
+	}
+
 	// Generate query embedding
-	queryEmb, err := e.embedder.Embed(ctx, query)
+	queryEmb, err := e.embedQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
 
-	// Perform vector search (trust filtering done after)
-	vecResults, err := e.db.VectorSearch(queryEmb, opts.Limit*3)
-	if err != nil {
-		return nil, fmt.Errorf("vector search failed: %w", err)
+	// With Explain, count how many candidates each filter excludes
+	var drops map[string]int
+	if opts.Explain {
+		drops = make(map[string]int)
 	}
 
-	// Perform FTS search for keyword matching
-	ftsIDs, _ := e.db.FTSSearch(query, opts.Limit*2)
-	ftsSet := make(map[string]bool)
-	for _, id := range ftsIDs {
-		ftsSet[id] = true
+	var results []types.SearchResult
+	for _, l := range e.layers {
+		layerResults, err := e.recallLayer(l, query, queryEmb, opts, drops)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		results = append(results, layerResults...)
 	}
 
-	// Build trust level set for filtering
-	trustSet := make(map[types.TrustLevel]bool)
-	for _, t := range opts.TrustLevels {
-		trustSet[t] = true
+	results = mergeResults(results)
+
+	if opts.Rerank {
+		if results, err = e.rerank(ctx, query, results, opts.Limit); err != nil {
+			warnRerank(err)
+		}
 	}
 
-	// Combine results with hybrid scoring
-	var results []types.SearchResult
-	seen := make(map[string]bool)
+	// Limit results, skipping near-duplicates if asked to
+	if opts.MMRLambda > 0 {
+		results = e.diversify(results, opts.MMRLambda, opts.Limit)
+	} else if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
 
-	for _, vr := range vecResults {
-		if seen[vr.MemoryID] {
-			continue
+	if opts.Explain {
+		filters := recallFilters(opts)
+		for i := range filters {
+			filters[i].Dropped = drops[filters[i].Name]
+		}
+		for _, r := range results {
+			r.Explanation.Filters = filters
 		}
-		seen[vr.MemoryID] = true
+	}
 
-		memory, err := e.db.GetMemory(vr.MemoryID)
-		if err != nil || memory == nil {
-			continue
+	// Increment access count
+	for _, r := range results {
+		if l, err := e.layer(r.Memory.Store); err == nil && !l.readOnly {
+			l.db.IncrementAccessCount(r.Memory.ID)
 		}
+	}
 
-		// Filter by trust level
-		if !trustSet[memory.Trust] {
-			continue
+	return results, nil
+}
+
+// recallLayer searches a single store, fusing vector and full-text matches
+// If drops is not nil, it receives how many candidates each filter excluded.
+func (e *Engine) recallLayer(l *layer, query string, queryEmb []float32, opts types.RecallOptions, drops map[string]int) ([]types.SearchResult, error) {
+	if drops != nil {
+		if err := e.countFilterDrops(l, query, queryEmb, opts, drops); err != nil {
+			return nil, err
 		}
+	}
+
+	// Perform vector search; filters apply inside the search so that a
+	// narrow filter still yields up to Limit results
+	var vecResults []db.VectorResult
+	var err error
+	if !l.keywordOnly.Load() {
+		vecResults, err = l.db.VectorSearch(queryEmb, opts.Limit*3, opts)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+	}
+
+	// Perform FTS search for keyword matching on any word of the query
+	var ftsResults []db.FTSResult
+	if ftsQuery := db.FreeTextQuery(query); ftsQuery != "" {
+		ftsResults, err = l.db.FTSSearch(ftsQuery, opts.Limit*3, opts)
+		if err != nil {
+			return nil, fmt.Errorf("full-text search failed: %w", err)
+		}
+	}
+
+	// Collect candidates from both searches
+	hits := make(map[string]*hit)
+	var order []*hit
+	candidate := func(id string) *hit {
+		h, ok := hits[id]
+		if !ok {
+			h = &hit{memoryID: id, distance: -1}
+			hits[id] = h
+			order = append(order, h)
+		}
+		return h
+	}
 
-		// Calculate hybrid score
-		// Convert L2 distance to similarity (0-1)
-		semanticScore := 1.0 - (vr.Distance / 2.0)
-		if semanticScore < 0 {
-			semanticScore = 0
+	for i, vr := range vecResults {
+		h := candidate(vr.MemoryID)
+		h.semanticRank = i + 1
+		h.distance = vr.Distance
+		h.semantic = similarity(vr.Distance)
+	}
+
+	bestBM25 := 0.0
+	for i, fr := range ftsResults {
+		h := candidate(fr.MemoryID)
+		h.keywordRank = i + 1
+		h.bm25 = fr.Score
+		if fr.Score > bestBM25 {
+			bestBM25 = fr.Score
 		}
+	}
 
-		// Keyword boost
-		keywordBoost := 0.0
-		if ftsSet[vr.MemoryID] {
-			keywordBoost = 0.15
+	now := timeNow()
+	var results []types.SearchResult
+	for _, h := range order {
+		memory, err := l.db.GetMemory(h.memoryID)
+		if err != nil || memory == nil {
+			continue
 		}
+		memory.Store = l.name
 
-		// Final score: 70% semantic + 30% keyword potential + boost
-		finalScore := semanticScore*0.7 + keywordBoost
+		if bestBM25 > 0 {
+			h.keyword = h.bm25 / bestBM25
+		}
+		if h.semanticRank == 0 && e.ranking.Method == RankingWeighted {
+			// Keyword-only hit: weigh in its similarity too, if it has a vector
+			if emb, err := l.db.GetEmbedding(h.memoryID); err == nil && emb != nil {
+				h.distance = l2Distance(queryEmb, emb)
+				h.semantic = similarity(h.distance)
+			}
+		}
 
-		if finalScore < opts.MinScore {
+		// The minimum score only applies to semantic evidence: a keyword hit
+		// contains a query word, but its fused score can't exceed the keyword
+		// weight and falls off with its rank
+		if h.keywordRank == 0 && h.semantic < opts.MinScore {
+			if drops != nil {
+				drops[FilterMinScore]++
+			}
 			continue
 		}
 
-		// Increment access count
-		e.db.IncrementAccessCount(memory.ID)
+		// Scale by how much this store is preferred
+		relevance := score(e.ranking, h) * l.weight
+
+		// Favor trusted, frequently used and recently updated knowledge
+		finalScore, explanation := applyScoring(e.scoring, relevance, memory, now)
 
-		results = append(results, types.SearchResult{
+		result := types.SearchResult{
 			Memory:    *memory,
 			Score:     finalScore,
-			MatchType: "hybrid",
-		})
-	}
-
-	// Limit results
-	if len(results) > opts.Limit {
-		results = results[:opts.Limit]
+			MatchType: h.matchType(),
+		}
+		if opts.Explain {
+			h.explain(explanation)
+			explanation.StoreWeight = l.weight
+			result.Explanation = explanation
+		}
+		results = append(results, result)
 	}
 
 	return results, nil
 }
 
-// Get retrieves a specific memory by ID
+// Get retrieves a specific memory by ID from whichever store holds it
 func (e *Engine) Get(id string) (*types.Memory, error) {
-	return e.db.GetMemory(id)
+	_, memory, err := e.locate(id)
+	return memory, err
 }
 
-// List returns memories matching filters
+// List returns memories matching filters across all stores, newest first
 func (e *Engine) List(opts types.RecallOptions) ([]*types.Memory, error) {
-	return e.db.ListMemories(opts)
+	var memories []*types.Memory
+	for _, l := range e.layers {
+		layerMemories, err := l.db.ListMemories(opts)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		for _, m := range layerMemories {
+			m.Store = l.name
+		}
+		memories = append(memories, layerMemories...)
+	}
+
+	sort.SliceStable(memories, func(i, j int) bool {
+		return memories[i].UpdatedAt.After(memories[j].UpdatedAt)
+	})
+	if opts.Limit > 0 && len(memories) > opts.Limit {
+		memories = memories[:opts.Limit]
+	}
+
+	return memories, nil
 }
 
 // Delete removes a memory
 func (e *Engine) Delete(id string) error {
-	return e.db.DeleteMemory(id)
+	l, _, err := e.locateWritable(id)
+	if err != nil {
+		return err
+	}
+	return l.db.DeleteMemory(id)
 }
 
 // Validate updates the trust level of a memory
 func (e *Engine) Validate(id string, trust types.TrustLevel) error {
-	return e.db.UpdateTrust(id, trust)
+	l, _, err := e.locateWritable(id)
+	if err != nil {
+		return err
+	}
+	return l.db.UpdateTrust(id, trust)
 }
 
-// Relate creates a relation between two memories
+// Update changes a memory's content, type or tags in place. The new state is
+// recorded as a revision, and the memory is re-embedded if its content changed.
+func (e *Engine) Update(ctx context.Context, id string, opts types.UpdateOptions) (*types.Memory, error) {
+	l, memory, err := e.locateWritable(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.ensureBaseRevision(l.db, memory); err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	contentChanged := opts.Content != "" && opts.Content != memory.Content
+	if opts.Content != "" {
+		memory.Content = opts.Content
+	}
+	if opts.Type != "" {
+		memory.Type = opts.Type
+	}
+	if opts.Tags != nil {
+		memory.Tags = opts.Tags
+	}
+	memory.UpdatedAt = timeNow()
+
+	if err := l.db.SaveMemory(memory); err != nil {
+		return nil, fmt.Errorf("failed to save memory: %w", err)
+	}
+	if err := e.saveRevision(l.db, memory, opts.Source); err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	if contentChanged {
+		e.embed(ctx, l.db, memory)
+	}
+
+	return memory, nil
+}
+
+// Relate creates a relation between two memories. The relation is saved in
+// the store holding the source memory.
 func (e *Engine) Relate(fromID, toID string, relType types.RelationType, note string) (*types.Relation, error) {
 	// Verify both memories exist
-	from, err := e.db.GetMemory(fromID)
+	l, from, err := e.locate(fromID)
 	if err != nil || from == nil {
 		return nil, fmt.Errorf("source memory not found: %s", fromID)
 	}
-	to, err := e.db.GetMemory(toID)
+	if l.readOnly {
+		return nil, fmt.Errorf("source memory %s is in read-only store %s", fromID, l.name)
+	}
+	_, to, err := e.locate(toID)
 	if err != nil || to == nil {
 		return nil, fmt.Errorf("target memory not found: %s", toID)
 	}
@@ -274,27 +632,64 @@ func (e *Engine) Relate(fromID, toID string, relType types.RelationType, note st
 		CreatedAt: timeNow(),
 	}
 
-	if err := e.db.SaveRelation(relation); err != nil {
+	if err := l.db.SaveRelation(relation); err != nil {
 		return nil, fmt.Errorf("failed to save relation: %w", err)
 	}
 
 	return relation, nil
 }
 
-// GetRelations returns all relations for a memory
-func (e *Engine) GetRelations(memoryID string) ([]*types.Relation, error) {
-	from, err := e.db.GetRelationsFrom(memoryID)
-	if err != nil {
-		return nil, err
+// Unrelate removes a relation from whichever store holds it and returns it
+func (e *Engine) Unrelate(relationID string) (*types.Relation, error) {
+	for _, l := range e.layers {
+		relation, err := l.db.GetRelation(relationID)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		if relation == nil {
+			continue
+		}
+		if l.readOnly {
+			return nil, fmt.Errorf("relation %s is in read-only store %s", relationID, l.name)
+		}
+		if err := l.db.DeleteRelation(relationID); err != nil {
+			return nil, fmt.Errorf("failed to delete relation: %w", err)
+		}
+		return relation, nil
 	}
-	to, err := e.db.GetRelationsTo(memoryID)
-	if err != nil {
-		return nil, err
+	return nil, fmt.Errorf("relation not found: %s", relationID)
+}
+
+// GetRelations returns all relations for a memory across all stores
+func (e *Engine) GetRelations(memoryID string) ([]*types.Relation, error) {
+	var relations []*types.Relation
+	for _, l := range e.layers {
+		from, err := l.db.GetRelationsFrom(memoryID)
+		if err != nil {
+			return nil, err
+		}
+		to, err := l.db.GetRelationsTo(memoryID)
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, from...)
+		relations = append(relations, to...)
 	}
-	return append(from, to...), nil
+	return relations, nil
 }
 
-// Stats returns engine statistics
+// Stats returns engine statistics summed over all stores
 func (e *Engine) Stats() (map[string]int, error) {
-	return e.db.Stats()
+	stats := make(map[string]int)
+	for _, l := range e.layers {
+		layerStats, err := l.db.Stats()
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		for k, v := range layerStats {
+			stats[k] += v
+		}
+	}
+	stats["stores"] = len(e.layers)
+	return stats, nil
 }
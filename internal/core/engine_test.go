@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// testDimensions is the size of the fake embedder's vectors
+const testDimensions = 8
+
+// fakeEmbedder embeds text as the vector set for it, or as a fixed vector
+// derived from the text, and counts the texts it was asked to embed
+type fakeEmbedder struct {
+	mu      sync.Mutex
+	model   string
+	vectors map[string][]float32
+	err     error
+	calls   int
+	lazy    bool // Report no dimension until something is embedded, like Ollama
+}
+
+func newFakeEmbedder() *fakeEmbedder {
+	return &fakeEmbedder{model: "fake", vectors: make(map[string][]float32)}
+}
+
+// set makes text embed as the normalized vector v
+func (f *fakeEmbedder) set(text string, v ...float32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.vectors[text] = unit(v)
+}
+
+func (f *fakeEmbedder) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeEmbedder) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := f.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		f.calls++
+		if v, ok := f.vectors[text]; ok {
+			vectors[i] = v
+			continue
+		}
+		// Unrelated texts get unrelated vectors
+		h := fnv.New64a()
+		h.Write([]byte(text))
+		seed := h.Sum64()
+		v := make([]float32, testDimensions)
+		for j := range v {
+			seed = seed*6364136223846793005 + 1442695040888963407
+			v[j] = float32(int64(seed>>33)%1000) - 500
+		}
+		vectors[i] = unit(v)
+	}
+	return vectors, nil
+}
+
+func (f *fakeEmbedder) Model() string { return f.model }
+func (f *fakeEmbedder) Dimensions() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lazy && f.calls == 0 {
+		return 0
+	}
+	return testDimensions
+}
+
+// unit pads v to testDimensions and scales it to length 1
+func unit(v []float32) []float32 {
+	out := make([]float32, testDimensions)
+	copy(out, v)
+	var norm float64
+	for _, x := range out {
+		norm += float64(x) * float64(x)
+	}
+	norm = math.Sqrt(norm)
+	for i := range out {
+		out[i] = float32(float64(out[i]) / norm)
+	}
+	return out
+}
+
+// newTestEngine opens an engine on a fresh project store in a temporary
+// directory, embedding with embedder. configure may adjust the config first.
+// The test is skipped if SQLite was built without FTS5 (go test -tags fts5).
+func newTestEngine(t *testing.T, embedder *fakeEmbedder, configure func(cfg *types.Config)) *Engine {
+	t.Helper()
+	cfg := &types.Config{DBPath: filepath.Join(t.TempDir(), "cortex.db")}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	e, err := open(cfg, embedder, true)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skipf("SQLite lacks FTS5, run the tests with -tags fts5: %v", err)
+		}
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+// store saves content in the default store and returns the result
+func store(t *testing.T, e *Engine, content string, opts types.StoreOptions) *types.StoreResult {
+	t.Helper()
+	result, err := e.Store(context.Background(), content, opts)
+	if err != nil {
+		t.Fatalf("Store(%q): %v", content, err)
+	}
+	return result
+}
+
+func TestRecallMinScore(t *testing.T) {
+	for _, method := range []string{RankingWeighted, RankingRRF} {
+		t.Run(method, func(t *testing.T) {
+			embedder := newFakeEmbedder()
+			e := newTestEngine(t, embedder, func(cfg *types.Config) {
+				cfg.Ranking = &types.RankingConfig{Method: method}
+			})
+			validated := types.StoreOptions{Trust: types.TrustValidated}
+
+			embedder.set("parser crash", 1)
+			embedder.set("the tokenizer overflows its buffer", 1, 0.2)
+			embedder.set("use tabs for indentation", -1)
+			store(t, e, "the tokenizer overflows its buffer", validated)
+			store(t, e, "use tabs for indentation", validated)
+
+			// Without embeddings these are found by keyword only
+			embedder.fail(errors.New("provider down"))
+			for _, content := range []string{"parser crash on empty input", "crash when the parser sees a BOM", "parser: crash in lookahead"} {
+				store(t, e, content, validated)
+			}
+			embedder.fail(nil)
+
+			results, err := e.Recall(context.Background(), "parser crash", types.RecallOptions{Limit: 10})
+			if err != nil {
+				t.Fatalf("Recall: %v", err)
+			}
+
+			var got []string
+			for _, r := range results {
+				got = append(got, r.MatchType+":"+r.Memory.Content)
+			}
+			sort.Strings(got)
+			want := []string{
+				"keyword:crash when the parser sees a BOM",
+				"keyword:parser crash on empty input",
+				"keyword:parser: crash in lookahead",
+				"semantic:the tokenizer overflows its buffer",
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("results:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Filter names reported in explanations
+const (
+	FilterType     = "type"
+	FilterTrust    = "trust"
+	FilterTags     = "tags"
+	FilterProject  = "project"
+	FilterTopicKey = "topic_key"
+	FilterMinScore = "min_score"
+)
+
+// recallFilters lists the filters a recall applies, with no drops counted yet
+func recallFilters(opts types.RecallOptions) []types.FilterStat {
+	var filters []types.FilterStat
+	if len(opts.Types) > 0 {
+		values := make([]string, len(opts.Types))
+		for i, t := range opts.Types {
+			values[i] = string(t)
+		}
+		filters = append(filters, types.FilterStat{Name: FilterType, Value: strings.Join(values, ",")})
+	}
+	if len(opts.TrustLevels) > 0 {
+		values := make([]string, len(opts.TrustLevels))
+		for i, t := range opts.TrustLevels {
+			values[i] = string(t)
+		}
+		filters = append(filters, types.FilterStat{Name: FilterTrust, Value: strings.Join(values, ",")})
+	}
+	if len(opts.Tags) > 0 {
+		filters = append(filters, types.FilterStat{Name: FilterTags, Value: strings.Join(opts.Tags, ",")})
+	}
+	if opts.Project != "" {
+		filters = append(filters, types.FilterStat{Name: FilterProject, Value: opts.Project})
+	}
+	if opts.TopicKey != "" {
+		filters = append(filters, types.FilterStat{Name: FilterTopicKey, Value: opts.TopicKey + "*"})
+	}
+	filters = append(filters, types.FilterStat{Name: FilterMinScore, Value: fmt.Sprintf("%.2f", opts.MinScore)})
+	return filters
+}
+
+// rejectedBy returns the names of the filters that exclude a memory,
+// mirroring the conditions the database applies during search
+func rejectedBy(opts types.RecallOptions, m *types.Memory) []string {
+	var names []string
+	if len(opts.Types) > 0 && !containsType(opts.Types, m.Type) {
+		names = append(names, FilterType)
+	}
+	if len(opts.TrustLevels) > 0 && !containsTrust(opts.TrustLevels, m.Trust) {
+		names = append(names, FilterTrust)
+	}
+	if len(opts.Tags) > 0 && !sharesTag(opts.Tags, m.Tags) {
+		names = append(names, FilterTags)
+	}
+	if opts.Project != "" && m.Metadata.Project != opts.Project {
+		names = append(names, FilterProject)
+	}
+	if opts.TopicKey != "" && !strings.HasPrefix(strings.ToLower(m.TopicKey), strings.ToLower(opts.TopicKey)) {
+		names = append(names, FilterTopicKey)
+	}
+	return names
+}
+
+// countFilterDrops repeats a layer's searches without filters and counts, for
+// each filter, how many of those candidates it excludes. A candidate excluded
+// by several filters counts for each of them.
+func (e *Engine) countFilterDrops(l *layer, query string, queryEmb []float32, opts types.RecallOptions, drops map[string]int) error {
+	if len(recallFilters(opts)) == 1 {
+		return nil // Only min_score, which recallLayer counts itself
+	}
+	var unfiltered types.RecallOptions
+
+	var vecResults []db.VectorResult
+	var err error
+	if !l.keywordOnly.Load() {
+		vecResults, err = l.db.VectorSearch(queryEmb, opts.Limit*3, unfiltered)
+		if err != nil {
+			return fmt.Errorf("vector search failed: %w", err)
+		}
+	}
+	var ftsResults []db.FTSResult
+	if ftsQuery := db.FreeTextQuery(query); ftsQuery != "" {
+		ftsResults, err = l.db.FTSSearch(ftsQuery, opts.Limit*3, unfiltered)
+		if err != nil {
+			return fmt.Errorf("full-text search failed: %w", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	check := func(id string) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		memory, err := l.db.GetMemory(id)
+		if err != nil || memory == nil {
+			return
+		}
+		for _, name := range rejectedBy(opts, memory) {
+			drops[name]++
+		}
+	}
+	for _, vr := range vecResults {
+		check(vr.MemoryID)
+	}
+	for _, fr := range ftsResults {
+		check(fr.MemoryID)
+	}
+
+	return nil
+}
+
+func containsType(list []types.MemoryType, t types.MemoryType) bool {
+	for _, v := range list {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTrust(list []types.TrustLevel, t types.TrustLevel) bool {
+	for _, v := range list {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func sharesTag(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if w == h {
+				return true
+			}
+		}
+	}
+	return false
+}
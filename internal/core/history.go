@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Resolve finds a memory by ID, falling back to an exact topic key match
+func (e *Engine) Resolve(idOrKey string) (*types.Memory, error) {
+	_, memory, err := e.locate(idOrKey)
+	if err != nil || memory != nil {
+		return memory, err
+	}
+
+	for _, l := range e.layers {
+		memory, err := l.db.GetMemoryByTopicKey(idOrKey)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		if memory != nil {
+			memory.Store = l.name
+			return memory, nil
+		}
+	}
+	return nil, nil
+}
+
+// History returns the revisions of a memory, oldest first
+func (e *Engine) History(memoryID string) ([]*types.Revision, error) {
+	l, memory, err := e.locate(memoryID)
+	if err != nil || memory == nil {
+		return nil, err
+	}
+	return l.db.GetRevisions(memoryID)
+}
+
+// Revision returns a single revision of a memory
+func (e *Engine) Revision(memoryID string, rev int) (*types.Revision, error) {
+	l, memory, err := e.locate(memoryID)
+	if err != nil {
+		return nil, err
+	}
+	if memory == nil {
+		return nil, fmt.Errorf("memory not found: %s", memoryID)
+	}
+
+	revision, err := l.db.GetRevision(memoryID, rev)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, fmt.Errorf("revision %d not found for memory %s", rev, memoryID)
+	}
+	return revision, nil
+}
+
+// Revert restores a memory's content, type, tags and trust from a past
+// revision. The restored state is appended as a new revision, so the revert
+// itself shows up in the history.
+func (e *Engine) Revert(ctx context.Context, memoryID string, rev int, author string) (*types.Memory, error) {
+	l, memory, err := e.locateWritable(memoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := e.Revision(memoryID, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.ensureBaseRevision(l.db, memory); err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	contentChanged := memory.Content != revision.Content
+	memory.Content = revision.Content
+	memory.Type = revision.Type
+	memory.Tags = revision.Tags
+	memory.Trust = revision.Trust
+	memory.UpdatedAt = timeNow()
+
+	if err := l.db.SaveMemory(memory); err != nil {
+		return nil, fmt.Errorf("failed to save memory: %w", err)
+	}
+	if err := e.saveRevision(l.db, memory, author); err != nil {
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	if contentChanged {
+		e.embed(ctx, l.db, memory)
+	}
+
+	return memory, nil
+}
+
+// saveRevision appends the memory's current state as a new revision
+func (e *Engine) saveRevision(store *db.DB, memory *types.Memory, author string) error {
+	if author == "" {
+		author = memory.Metadata.Source
+	}
+
+	_, err := store.SaveRevision(&types.Revision{
+		MemoryID:  memory.ID,
+		Content:   memory.Content,
+		Type:      memory.Type,
+		Tags:      memory.Tags,
+		Trust:     memory.Trust,
+		Author:    author,
+		CreatedAt: memory.UpdatedAt,
+	})
+	return err
+}
+
+// ensureBaseRevision records the memory's current state as revision 1 if it
+// has no history yet (memories stored before revisions were tracked), so the
+// version about to be overwritten is not lost
+func (e *Engine) ensureBaseRevision(store *db.DB, memory *types.Memory) error {
+	count, err := store.CountRevisions(memory.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return e.saveRevision(store, memory, memory.Metadata.Source)
+}
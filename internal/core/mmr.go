@@ -0,0 +1,62 @@
+package core
+
+import (
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// diversify picks up to limit results by maximal marginal relevance: each
+// pick maximizes lambda*score - (1-lambda)*similarity to the closest result
+// already picked, so near-duplicates give way to other useful context.
+// Similarity is the cosine of the stored embeddings, as for dedupe; results
+// without one are treated as unlike everything else.
+func (e *Engine) diversify(results []types.SearchResult, lambda float64, limit int) []types.SearchResult {
+	if len(results) <= 1 {
+		return results
+	}
+
+	embs := make([][]float32, len(results))
+	for i, r := range results {
+		l, err := e.layer(r.Memory.Store)
+		if err != nil {
+			continue
+		}
+		if emb, err := l.db.GetEmbedding(r.Memory.ID); err == nil {
+			embs[i] = emb
+		}
+	}
+
+	// maxSim[i] is candidate i's highest similarity to a picked result
+	maxSim := make([]float64, len(results))
+	picked := make([]bool, len(results))
+	var selected []types.SearchResult
+
+	for len(selected) < limit && len(selected) < len(results) {
+		best, bestMMR := -1, 0.0
+		for i, r := range results {
+			if picked[i] {
+				continue
+			}
+			mmr := lambda*r.Score - (1-lambda)*maxSim[i]
+			if best < 0 || mmr > bestMMR {
+				best, bestMMR = i, mmr
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, results[best])
+
+		if embs[best] == nil {
+			continue
+		}
+		for i := range results {
+			if picked[i] || embs[i] == nil {
+				continue
+			}
+			if s := cosine(l2Distance(embs[i], embs[best])); s > maxSim[i] {
+				maxSim[i] = s
+			}
+		}
+	}
+
+	return selected
+}
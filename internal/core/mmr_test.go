@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestDiversify(t *testing.T) {
+	// Vectors of the candidates; "plain" has no embedding
+	vectors := map[string][]float32{
+		"hooks":      {1, 0},
+		"hooks-copy": {1, 0.05},
+		"hooks-near": {0.8, 0.6},
+		"tabs":       {0, 1},
+	}
+
+	tests := []struct {
+		name    string
+		results []string // Candidates, best score first
+		scores  []float64
+		lambda  float64
+		limit   int
+		want    string
+	}{
+		{
+			name:    "lambda 1 keeps the score order",
+			results: []string{"hooks", "hooks-copy", "tabs"},
+			scores:  []float64{0.9, 0.88, 0.5},
+			lambda:  1,
+			limit:   2,
+			want:    "hooks,hooks-copy",
+		},
+		{
+			name:    "near-duplicate gives way to an unrelated result",
+			results: []string{"hooks", "hooks-copy", "tabs"},
+			scores:  []float64{0.9, 0.88, 0.5},
+			lambda:  0.7,
+			limit:   2,
+			want:    "hooks,tabs",
+		},
+		{
+			name:    "related but distinct result is kept over an unrelated one",
+			results: []string{"hooks", "hooks-near", "tabs"},
+			scores:  []float64{0.9, 0.88, 0.5},
+			lambda:  0.7,
+			limit:   2,
+			want:    "hooks,hooks-near",
+		},
+		{
+			name:    "result without an embedding is unlike everything",
+			results: []string{"hooks", "hooks-copy", "plain"},
+			scores:  []float64{0.9, 0.88, 0.3},
+			lambda:  0.5,
+			limit:   3,
+			want:    "hooks,plain,hooks-copy",
+		},
+		{
+			name:    "limit above the candidates",
+			results: []string{"hooks", "tabs"},
+			scores:  []float64{0.9, 0.5},
+			lambda:  0.5,
+			limit:   5,
+			want:    "hooks,tabs",
+		},
+	}
+
+	embedder := newFakeEmbedder()
+	e := newTestEngine(t, embedder, nil)
+	memories := make(map[string]types.Memory)
+	for _, name := range []string{"hooks", "hooks-copy", "hooks-near", "tabs", "plain"} {
+		if v, ok := vectors[name]; ok {
+			embedder.set(name, v...)
+		} else {
+			embedder.fail(errors.New("provider down"))
+		}
+		memories[name] = *store(t, e, name, types.StoreOptions{AllowDuplicate: true}).Memory
+		embedder.fail(nil)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in []types.SearchResult
+			names := make(map[string]string)
+			for i, name := range tt.results {
+				m := memories[name]
+				m.Store = ProjectStore
+				names[m.ID] = name
+				in = append(in, types.SearchResult{Memory: m, Score: tt.scores[i]})
+			}
+
+			var order []string
+			for _, r := range e.diversify(in, tt.lambda, tt.limit) {
+				order = append(order, names[r.Memory.ID])
+			}
+			if got := strings.Join(order, ","); got != tt.want {
+				t.Errorf("picked %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/constantino-dev/cortex/internal/db"
+)
+
+// Retry backoff for failed embeddings: the delay doubles with each failed
+// attempt, from pendingBaseDelay up to pendingMaxDelay
+const (
+	pendingBaseDelay = time.Minute
+	pendingMaxDelay  = 6 * time.Hour
+)
+
+// PendingOptions configures a retry of failed embeddings
+type PendingOptions struct {
+	Force         bool // Retry even memories whose backoff has not elapsed
+	Limit         int  // Most memories to retry per store (0: all)
+	StopOnFailure bool // Stop at the first failure, e.g. while the provider is down
+}
+
+// PendingStats counts the outcome of a retry
+type PendingStats struct {
+	Embedded  int `json:"embedded"`
+	Failed    int `json:"failed"`
+	Remaining int `json:"remaining"` // Still queued afterwards
+}
+
+// retryDelay returns how long to wait after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := pendingBaseDelay
+	for i := 1; i < attempts && delay < pendingMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > pendingMaxDelay {
+		delay = pendingMaxDelay
+	}
+	return delay
+}
+
+// queueEmbedding records a failed embedding so it is retried later
+func (e *Engine) queueEmbedding(store *db.DB, memoryID string, cause error) {
+	attempts := 1
+	if p, err := store.GetPendingEmbedding(memoryID); err == nil && p != nil {
+		attempts = p.Attempts + 1
+	}
+
+	err := store.QueueEmbedding(&db.PendingEmbedding{
+		MemoryID:    memoryID,
+		Attempts:    attempts,
+		LastError:   cause.Error(),
+		NextAttempt: timeNow().Add(retryDelay(attempts)),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to queue embedding retry: %v\n", err)
+	}
+}
+
+// EmbedPending retries the embeddings that failed earlier, in every writable store
+func (e *Engine) EmbedPending(ctx context.Context, opts PendingOptions) (PendingStats, error) {
+	var stats PendingStats
+
+	for _, l := range e.layers {
+		if l.readOnly {
+			continue
+		}
+
+		var due time.Time
+		if !opts.Force {
+			due = timeNow()
+		}
+		pending, err := l.db.ListPendingEmbeddings(due, opts.Limit)
+		if err != nil {
+			return stats, fmt.Errorf("store %s: failed to list pending embeddings: %w", l.name, err)
+		}
+
+		for _, p := range pending {
+			memory, err := l.db.GetMemory(p.MemoryID)
+			if err != nil {
+				return stats, fmt.Errorf("store %s: %w", l.name, err)
+			}
+			if memory == nil {
+				l.db.DeletePendingEmbedding(p.MemoryID)
+				continue
+			}
+
+			embedding, err := e.embedContent(ctx, l.db, memory.Content)
+			if err == nil {
+				err = l.db.SaveEmbedding(memory.ID, embedding, e.embedder.Model())
+			}
+			if err != nil {
+				e.queueEmbedding(l.db, memory.ID, err)
+				stats.Failed++
+				if opts.StopOnFailure {
+					break
+				}
+				continue
+			}
+			stats.Embedded++
+		}
+
+		remaining, err := l.db.CountPendingEmbeddings()
+		if err != nil {
+			return stats, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		stats.Remaining += remaining
+
+		if opts.StopOnFailure && stats.Failed > 0 {
+			break
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedPending(t *testing.T) {
+	embedder := newFakeEmbedder()
+	e := newTestEngine(t, embedder, nil)
+	ctx := context.Background()
+
+	// A failed embedding is stored anyway and queued for a minute later
+	embedder.fail(errors.New("provider down"))
+	memory := store(t, e, "tabs over spaces", types.StoreOptions{}).Memory
+
+	p, err := e.db.GetPendingEmbedding(memory.ID)
+	if err != nil || p == nil {
+		t.Fatalf("memory not queued: %v, %v", p, err)
+	}
+	if p.Attempts != 1 || p.LastError != "provider down" {
+		t.Errorf("queued %d attempts with error %q, want 1 and provider down", p.Attempts, p.LastError)
+	}
+	if wait := time.Until(p.NextAttempt); wait < 50*time.Second || wait > time.Minute {
+		t.Errorf("next attempt in %s, want a minute", wait)
+	}
+
+	// Nothing is due yet
+	stats, err := e.EmbedPending(ctx, PendingOptions{})
+	if err != nil {
+		t.Fatalf("EmbedPending: %v", err)
+	}
+	if stats != (PendingStats{Remaining: 1}) {
+		t.Errorf("stats before the backoff elapsed = %+v", stats)
+	}
+
+	// Another failure doubles the backoff
+	stats, err = e.EmbedPending(ctx, PendingOptions{Force: true})
+	if err != nil {
+		t.Fatalf("EmbedPending: %v", err)
+	}
+	if stats != (PendingStats{Failed: 1, Remaining: 1}) {
+		t.Errorf("stats after a failed retry = %+v", stats)
+	}
+	p, _ = e.db.GetPendingEmbedding(memory.ID)
+	if p == nil || p.Attempts != 2 {
+		t.Fatalf("entry after a failed retry = %+v, want 2 attempts", p)
+	}
+	if wait := time.Until(p.NextAttempt); wait < 110*time.Second || wait > 2*time.Minute {
+		t.Errorf("next attempt in %s, want two minutes", wait)
+	}
+
+	// A successful retry embeds the memory and removes its entry
+	embedder.fail(nil)
+	stats, err = e.EmbedPending(ctx, PendingOptions{Force: true})
+	if err != nil {
+		t.Fatalf("EmbedPending: %v", err)
+	}
+	if stats != (PendingStats{Embedded: 1}) {
+		t.Errorf("stats after a successful retry = %+v", stats)
+	}
+	if p, err := e.db.GetPendingEmbedding(memory.ID); err != nil || p != nil {
+		t.Errorf("entry still queued after a successful retry: %+v, %v", p, err)
+	}
+
+	vector, _ := embedder.Embed(ctx, "tabs over spaces")
+	nearest, err := e.db.VectorSearch(vector, 1, types.RecallOptions{})
+	if err != nil || len(nearest) != 1 || nearest[0].MemoryID != memory.ID {
+		t.Errorf("VectorSearch = %v, %v, want the retried memory", nearest, err)
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Ranking methods
+const (
+	RankingWeighted = "weighted" // Weighted sum of semantic similarity and normalized BM25
+	RankingRRF      = "rrf"      // Reciprocal rank fusion of the two result lists
+)
+
+// Match types reported on search results
+const (
+	MatchSemantic = "semantic" // Found by vector search only
+	MatchKeyword  = "keyword"  // Found by full-text search only
+	MatchHybrid   = "hybrid"   // Found by both
+)
+
+// Default ranking parameters
+const (
+	DefaultSemanticWeight = 0.7
+	DefaultKeywordWeight  = 0.3
+	DefaultRRFK           = 60
+)
+
+// Default scoring parameters
+const (
+	DefaultTrustWeight   = 0.1
+	DefaultAccessWeight  = 0.05
+	DefaultRecencyWeight = 0.05
+	DefaultHalfLifeDays  = 30
+)
+
+// accessSaturation is the access count at which the access factor reaches 1
+const accessSaturation = 100
+
+// resolveRanking fills in defaults for unset ranking parameters
+func resolveRanking(cfg *types.RankingConfig) (types.RankingConfig, error) {
+	var r types.RankingConfig
+	if cfg != nil {
+		r = *cfg
+	}
+
+	switch r.Method {
+	case "":
+		r.Method = RankingWeighted
+	case RankingWeighted, RankingRRF:
+	default:
+		return r, fmt.Errorf("unknown ranking method: %s", r.Method)
+	}
+	if r.SemanticWeight < 0 || r.KeywordWeight < 0 {
+		return r, fmt.Errorf("ranking weights must not be negative")
+	}
+	if r.SemanticWeight == 0 && r.KeywordWeight == 0 {
+		r.SemanticWeight, r.KeywordWeight = DefaultSemanticWeight, DefaultKeywordWeight
+	}
+	if r.RRFK <= 0 {
+		r.RRFK = DefaultRRFK
+	}
+
+	return r, nil
+}
+
+// resolveScoring fills in defaults for the scoring model. Without a config
+// all factors use their default weights; with one, weights are taken as given.
+func resolveScoring(cfg *types.ScoringConfig) (types.ScoringConfig, error) {
+	if cfg == nil {
+		return types.ScoringConfig{
+			TrustWeight:   DefaultTrustWeight,
+			AccessWeight:  DefaultAccessWeight,
+			RecencyWeight: DefaultRecencyWeight,
+			HalfLifeDays:  DefaultHalfLifeDays,
+		}, nil
+	}
+
+	s := *cfg
+	if s.TrustWeight < 0 || s.AccessWeight < 0 || s.RecencyWeight < 0 {
+		return s, fmt.Errorf("scoring weights must not be negative")
+	}
+	if s.TrustWeight+s.AccessWeight+s.RecencyWeight >= 1 {
+		return s, fmt.Errorf("scoring weights must add up to less than 1, leaving room for relevance")
+	}
+	if s.HalfLifeDays < 0 {
+		return s, fmt.Errorf("half_life_days must not be negative")
+	}
+	if s.HalfLifeDays == 0 {
+		s.HalfLifeDays = DefaultHalfLifeDays
+	}
+
+	return s, nil
+}
+
+// applyScoring blends a memory's relevance with its trust level, access count
+// and recency into a 0-1 score, and explains each factor's contribution
+func applyScoring(s types.ScoringConfig, relevance float64, m *types.Memory, now time.Time) (float64, *types.Explanation) {
+	trust := trustValue(m.Trust)
+
+	access := math.Log1p(float64(m.AccessCnt)) / math.Log1p(accessSaturation)
+	if access > 1 {
+		access = 1
+	}
+
+	recency := 0.0
+	if !m.UpdatedAt.IsZero() {
+		ageDays := now.Sub(m.UpdatedAt).Hours() / 24
+		if ageDays < 0 {
+			ageDays = 0
+		}
+		recency = math.Pow(0.5, ageDays/s.HalfLifeDays)
+	}
+
+	relevanceWeight := 1 - s.TrustWeight - s.AccessWeight - s.RecencyWeight
+	explanation := &types.Explanation{Factors: []types.ScoreFactor{
+		{Name: "relevance", Value: relevance, Contribution: relevanceWeight * relevance},
+		{Name: "trust", Value: trust, Contribution: s.TrustWeight * trust},
+		{Name: "access", Value: access, Contribution: s.AccessWeight * access},
+		{Name: "recency", Value: recency, Contribution: s.RecencyWeight * recency},
+	}}
+
+	var total float64
+	for _, f := range explanation.Factors {
+		total += f.Contribution
+	}
+	return total, explanation
+}
+
+// trustValue rates a trust level from 0 (proposed) to 1 (proven)
+func trustValue(t types.TrustLevel) float64 {
+	switch t {
+	case types.TrustProven:
+		return 1
+	case types.TrustValidated:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// hit is a candidate memory found by vector search, full-text search or both
+type hit struct {
+	memoryID     string
+	distance     float64 // L2 distance to the query (-1: unknown)
+	semanticRank int     // 1-based position in the vector results (0: not found)
+	keywordRank  int     // 1-based position in the full-text results (0: not found)
+	semantic     float64 // Similarity to the query, 0-1
+	bm25         float64 // BM25 relevance (higher is better)
+	keyword      float64 // BM25 normalized against the best keyword hit, 0-1
+}
+
+// matchType reports which searches found the hit
+func (h *hit) matchType() string {
+	switch {
+	case h.semanticRank > 0 && h.keywordRank > 0:
+		return MatchHybrid
+	case h.keywordRank > 0:
+		return MatchKeyword
+	default:
+		return MatchSemantic
+	}
+}
+
+// explain records the hit's retrieval evidence in an explanation
+func (h *hit) explain(ex *types.Explanation) {
+	if h.distance >= 0 {
+		d := h.distance
+		ex.Distance = &d
+	}
+	ex.Semantic = h.semantic
+	ex.SemanticRank = h.semanticRank
+	ex.KeywordRank = h.keywordRank
+	ex.BM25 = h.bm25
+	ex.Keyword = h.keyword
+}
+
+// score fuses a hit's semantic and keyword evidence into a 0-1 score
+func score(r types.RankingConfig, h *hit) float64 {
+	total := r.SemanticWeight + r.KeywordWeight
+
+	if r.Method == RankingRRF {
+		// Normalized so that ranking first in both lists scores 1
+		var s float64
+		if h.semanticRank > 0 {
+			s += r.SemanticWeight / (r.RRFK + float64(h.semanticRank))
+		}
+		if h.keywordRank > 0 {
+			s += r.KeywordWeight / (r.RRFK + float64(h.keywordRank))
+		}
+		return s * (r.RRFK + 1) / total
+	}
+
+	return (r.SemanticWeight*h.semantic + r.KeywordWeight*h.keyword) / total
+}
+
+// similarity converts an L2 distance between unit vectors to a 0-1 similarity
+func similarity(distance float64) float64 {
+	s := 1.0 - distance/2.0
+	if s < 0 {
+		return 0
+	}
+	return s
+}
+
+// l2Distance returns the Euclidean distance between two vectors
+func l2Distance(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return math.Inf(1)
+	}
+	var sum float64
+	for i := range a {
+		d := float64(a[i] - b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
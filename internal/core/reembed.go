@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultReembedBatchSize is the number of memories embedded per provider call
+const DefaultReembedBatchSize = 64
+
+// ReembedProgress reports how far a re-embed has come
+type ReembedProgress struct {
+	Store    string // Store being re-embedded
+	Total    int    // Memories that needed a new embedding when the run started
+	Done     int    // Memories embedded so far in this run
+	Restored int    // Vectors carried over from embeddings already on the current model
+}
+
+// Reembed brings every memory in every writable store onto the configured
+// embedding model and rebuilds the vector indexes at the model's dimension.
+// Read-only stores on another model are left to keyword-only recall.
+// Memories whose embedding already comes from the current model are skipped,
+// so an interrupted run resumes where it stopped. progress, if non-nil, is
+// called after every batch.
+func (e *Engine) Reembed(ctx context.Context, batchSize int, progress func(ReembedProgress)) ([]ReembedProgress, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultReembedBatchSize
+	}
+
+	var results []ReembedProgress
+	for _, l := range e.layers {
+		if l.readOnly {
+			continue
+		}
+		p, err := e.reembedLayer(ctx, l, batchSize, progress)
+		results = append(results, p)
+		if err != nil {
+			return results, fmt.Errorf("store %s: %w", l.name, err)
+		}
+	}
+
+	return results, nil
+}
+
+func (e *Engine) reembedLayer(ctx context.Context, l *layer, batchSize int, progress func(ReembedProgress)) (ReembedProgress, error) {
+	model := e.embedder.Model()
+	p := ReembedProgress{Store: l.name}
+
+	total, err := l.db.CountStaleEmbeddings(model)
+	if err != nil {
+		return p, fmt.Errorf("failed to count stale embeddings: %w", err)
+	}
+	p.Total = total
+
+	dimensions, err := e.dimensions(ctx)
+	if err != nil {
+		return p, err
+	}
+	restored, err := l.db.RebuildVectorIndex(model, dimensions)
+	if err != nil {
+		return p, fmt.Errorf("failed to rebuild vector index: %w", err)
+	}
+	p.Restored = restored
+	if progress != nil {
+		progress(p)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+
+		batch, err := l.db.ListStaleEmbeddings(model, batchSize)
+		if err != nil {
+			return p, fmt.Errorf("failed to list stale embeddings: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		texts := make([]string, len(batch))
+		for i, m := range batch {
+			texts[i] = m.Content
+		}
+
+		vectors, err := e.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return p, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return p, fmt.Errorf("provider returned %d embeddings for %d memories", len(vectors), len(batch))
+		}
+
+		for i, m := range batch {
+			if err := l.db.SaveEmbedding(m.ID, vectors[i], model); err != nil {
+				return p, fmt.Errorf("failed to save embedding for %s: %w", m.ID, err)
+			}
+		}
+
+		p.Done += len(batch)
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	if err := l.db.FinishReembed(); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Reranker rescores recall candidates by how well they answer the query.
+// It sees the full text of each candidate, unlike the vector and keyword
+// indexes, so it can prefer the exact fix for an error over a memory that is
+// merely about the same topic.
+type Reranker interface {
+	// Rerank returns a relevance score between 0 and 1 for each candidate, in order
+	Rerank(ctx context.Context, query string, candidates []*types.Memory) ([]float64, error)
+
+	// Name identifies the reranker
+	Name() string
+}
+
+// Reranker providers
+const (
+	RerankHeuristic = "heuristic"
+	RerankLLM       = "llm"
+)
+
+// Default reranking parameters
+const (
+	DefaultRerankCandidates = 20
+	DefaultRerankWeight     = 0.7
+)
+
+// newReranker builds the reranker described by the config
+func newReranker(cfg *types.Config) (Reranker, error) {
+	var rc types.RerankConfig
+	if cfg.Rerank != nil {
+		rc = *cfg.Rerank
+	}
+
+	switch rc.Provider {
+	case RerankHeuristic, "":
+		return &HeuristicReranker{}, nil
+	case RerankLLM:
+		apiKey := rc.APIKey
+		if apiKey == "" {
+			apiKey = cfg.OpenAIKey
+		}
+		if apiKey == "" && rc.BaseURL == "" {
+			return nil, fmt.Errorf("LLM reranker needs an API key or a base_url")
+		}
+		return NewLLMReranker(apiKey, rc.BaseURL, rc.Model), nil
+	default:
+		return nil, fmt.Errorf("unknown rerank provider: %s", rc.Provider)
+	}
+}
+
+// SetReranker replaces the reranker used by Recall, e.g. with a fake in tests
+func (e *Engine) SetReranker(r Reranker) {
+	e.reranker = r
+}
+
+// rerank rescores the top candidates of a merged, sorted result list and
+// re-sorts them. The final score blends retrieval and reranker scores.
+// Results beyond the candidate window keep their order after the reranked ones.
+func (e *Engine) rerank(ctx context.Context, query string, results []types.SearchResult, limit int) ([]types.SearchResult, error) {
+	n, weight := DefaultRerankCandidates, DefaultRerankWeight
+	if rc := e.config.Rerank; rc != nil {
+		if rc.Candidates > 0 {
+			n = rc.Candidates
+		}
+		if rc.Weight > 0 && rc.Weight <= 1 {
+			weight = rc.Weight
+		}
+	}
+	if n < limit {
+		n = limit
+	}
+	if n > len(results) {
+		n = len(results)
+	}
+	if n == 0 {
+		return results, nil
+	}
+
+	candidates := make([]*types.Memory, n)
+	for i := range candidates {
+		candidates[i] = &results[i].Memory
+	}
+
+	scores, err := e.reranker.Rerank(ctx, query, candidates)
+	if err != nil {
+		return results, fmt.Errorf("%s reranker: %w", e.reranker.Name(), err)
+	}
+	if len(scores) != n {
+		return results, fmt.Errorf("%s reranker returned %d scores for %d candidates", e.reranker.Name(), len(scores), n)
+	}
+
+	for i, s := range scores {
+		if s < 0 {
+			s = 0
+		} else if s > 1 {
+			s = 1
+		}
+		results[i].Score = (1-weight)*results[i].Score + weight*s
+		if ex := results[i].Explanation; ex != nil {
+			for j := range ex.Factors {
+				ex.Factors[j].Contribution *= 1 - weight
+			}
+			ex.Factors = append(ex.Factors, types.ScoreFactor{Name: "rerank", Value: s, Contribution: weight * s})
+		}
+	}
+	sort.SliceStable(results[:n], func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// HeuristicReranker is a deterministic, local reranker. It rewards candidates
+// that contain the query's words, contain the query verbatim, and are error
+// memories when the query looks like an error message.
+type HeuristicReranker struct{}
+
+// Name identifies the reranker
+func (h *HeuristicReranker) Name() string {
+	return RerankHeuristic
+}
+
+// errorQuery matches queries that look like error messages or stack traces
+var errorQuery = regexp.MustCompile(`(?i)\b(error|exception|panic|fatal|failed|failure|traceback|refused|denied|undefined|cannot|timeout)\b|\w+\.\w+:\d+`)
+
+// Rerank scores each candidate: 60% share of query words it contains, 25% if
+// it contains the whole query, 15% if both it and the query are about an error
+func (h *HeuristicReranker) Rerank(ctx context.Context, query string, candidates []*types.Memory) ([]float64, error) {
+	queryWords := uniqueWords(query)
+	normalizedQuery := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	isError := errorQuery.MatchString(query)
+
+	scores := make([]float64, len(candidates))
+	for i, m := range candidates {
+		text := strings.ToLower(m.Content + " " + m.TopicKey + " " + strings.Join(m.Tags, " "))
+		words := make(map[string]bool)
+		for _, w := range uniqueWords(text) {
+			words[w] = true
+		}
+
+		var score float64
+		if len(queryWords) > 0 {
+			found := 0
+			for _, w := range queryWords {
+				if words[w] {
+					found++
+				}
+			}
+			score += 0.6 * float64(found) / float64(len(queryWords))
+		}
+		if normalizedQuery != "" && strings.Contains(strings.Join(strings.Fields(text), " "), normalizedQuery) {
+			score += 0.25
+		}
+		if isError && m.Type == types.TypeError {
+			score += 0.15
+		}
+		scores[i] = score
+	}
+
+	return scores, nil
+}
+
+// uniqueWords returns the distinct lower-case words of text
+func uniqueWords(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r > 127)
+	}) {
+		if !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// warnRerank reports a failed rerank; recall then keeps the retrieval order
+func warnRerank(err error) {
+	fmt.Fprintf(os.Stderr, "warning: reranking failed, using retrieval order: %v\n", err)
+}
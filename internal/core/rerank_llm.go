@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultRerankModel = "gpt-4o-mini"
+
+// rerankPrompt instructs the model to grade candidates; it must answer with JSON only
+const rerankPrompt = `You rank memories from a developer knowledge base by how well they answer a query.
+Rate each numbered memory from 0 (irrelevant) to 10 (directly answers the query).
+Reply with only a JSON object: {"scores": [<one number per memory, in order>]}`
+
+// LLMReranker asks a chat model on an OpenAI-compatible API to grade candidates
+type LLMReranker struct {
+	client *openai.Client
+	model  string
+}
+
+// NewLLMReranker creates a reranker. An empty baseURL uses OpenAI and an
+// empty model uses gpt-4o-mini.
+func NewLLMReranker(apiKey, baseURL, model string) *LLMReranker {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	if model == "" {
+		model = defaultRerankModel
+	}
+	return &LLMReranker{
+		client: openai.NewClientWithConfig(cfg),
+		model:  model,
+	}
+}
+
+// Name identifies the reranker
+func (r *LLMReranker) Name() string {
+	return RerankLLM
+}
+
+// Rerank grades all candidates in a single chat completion
+func (r *LLMReranker) Rerank(ctx context.Context, query string, candidates []*types.Memory) ([]float64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Query: %s\n\nMemories:\n", query)
+	for i, m := range candidates {
+		fmt.Fprintf(&b, "\n[%d] (%s", i+1, m.Type)
+		if m.TopicKey != "" {
+			fmt.Fprintf(&b, ", %s", m.TopicKey)
+		}
+		fmt.Fprintf(&b, ") %s\n", truncateRunes(m.Content, 1000))
+	}
+
+	resp, err := r.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       r.model,
+		Temperature: 0,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: rerankPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: b.String()},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("chat completion error: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no completion returned")
+	}
+
+	grades, err := parseRerankScores(resp.Choices[0].Message.Content)
+	if err != nil {
+		return nil, err
+	}
+	if len(grades) != len(candidates) {
+		return nil, fmt.Errorf("model graded %d memories, expected %d", len(grades), len(candidates))
+	}
+
+	scores := make([]float64, len(grades))
+	for i, g := range grades {
+		scores[i] = g / 10
+	}
+	return scores, nil
+}
+
+// parseRerankScores extracts the scores from a reply, tolerating code fences
+// or prose around the JSON and a bare array instead of an object
+func parseRerankScores(reply string) ([]float64, error) {
+	if start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); start >= 0 && end > start {
+		var obj struct {
+			Scores []float64 `json:"scores"`
+		}
+		if err := json.Unmarshal([]byte(reply[start:end+1]), &obj); err == nil && obj.Scores != nil {
+			return obj.Scores, nil
+		}
+	}
+	if start, end := strings.Index(reply, "["), strings.LastIndex(reply, "]"); start >= 0 && end > start {
+		var scores []float64
+		if err := json.Unmarshal([]byte(reply[start:end+1]), &scores); err == nil {
+			return scores, nil
+		}
+	}
+	return nil, fmt.Errorf("could not parse scores from reply: %s", truncateRunes(reply, 200))
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// fakeReranker scores candidates by ID and records what it was asked
+type fakeReranker struct {
+	scores map[string]float64
+	err    error
+	extra  bool // Return one score too many
+
+	seen []string
+}
+
+func (f *fakeReranker) Name() string { return "fake" }
+
+func (f *fakeReranker) Rerank(ctx context.Context, query string, candidates []*types.Memory) ([]float64, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	scores := make([]float64, len(candidates))
+	for i, m := range candidates {
+		f.seen = append(f.seen, m.ID)
+		scores[i] = f.scores[m.ID]
+	}
+	if f.extra {
+		scores = append(scores, 0)
+	}
+	return scores, nil
+}
+
+// results builds a sorted result list with the given IDs and scores
+func results(pairs ...interface{}) []types.SearchResult {
+	var out []types.SearchResult
+	for i := 0; i < len(pairs); i += 2 {
+		out = append(out, types.SearchResult{
+			Memory: types.Memory{ID: pairs[i].(string)},
+			Score:  pairs[i+1].(float64),
+		})
+	}
+	return out
+}
+
+func ids(results []types.SearchResult) string {
+	var s []string
+	for _, r := range results {
+		s = append(s, r.Memory.ID)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestRerank(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *types.RerankConfig
+		reranker *fakeReranker
+		results  []types.SearchResult
+		limit    int
+		want     string  // Resulting order
+		seen     string  // Candidates the reranker saw
+		topScore float64 // Score of the first result, if not 0
+		wantErr  string
+	}{
+		{
+			name:     "reranker score outweighs retrieval score",
+			reranker: &fakeReranker{scores: map[string]float64{"a": 0, "b": 1}},
+			results:  results("a", 0.9, "b", 0.5),
+			limit:    2,
+			want:     "b,a",
+			seen:     "a,b",
+			topScore: 0.3*0.5 + 0.7*1,
+		},
+		{
+			name:     "weight from config",
+			config:   &types.RerankConfig{Weight: 0.1},
+			reranker: &fakeReranker{scores: map[string]float64{"a": 0, "b": 1}},
+			results:  results("a", 0.9, "b", 0.5),
+			limit:    2,
+			want:     "a,b",
+			topScore: 0.9 * 0.9,
+		},
+		{
+			name:     "results beyond the candidate window keep their place",
+			config:   &types.RerankConfig{Candidates: 2},
+			reranker: &fakeReranker{scores: map[string]float64{"a": 0, "b": 1, "c": 1}},
+			results:  results("a", 0.9, "b", 0.8, "c", 0.1),
+			limit:    1,
+			want:     "b,a,c",
+			seen:     "a,b",
+		},
+		{
+			name:     "candidate window is at least the limit",
+			config:   &types.RerankConfig{Candidates: 1},
+			reranker: &fakeReranker{},
+			results:  results("a", 0.9, "b", 0.8, "c", 0.1),
+			limit:    2,
+			want:     "a,b,c",
+			seen:     "a,b",
+		},
+		{
+			name:     "scores are clamped to 0-1",
+			reranker: &fakeReranker{scores: map[string]float64{"a": -3, "b": 7}},
+			results:  results("a", 0.9, "b", 0.5),
+			limit:    2,
+			want:     "b,a",
+			topScore: 0.3*0.5 + 0.7*1,
+		},
+		{
+			name:     "no results",
+			reranker: &fakeReranker{},
+			limit:    5,
+		},
+		{
+			name:     "reranker error keeps retrieval order",
+			reranker: &fakeReranker{err: errors.New("boom")},
+			results:  results("a", 0.9, "b", 0.5),
+			limit:    2,
+			want:     "a,b",
+			wantErr:  "fake reranker: boom",
+		},
+		{
+			name:     "wrong number of scores keeps retrieval order",
+			reranker: &fakeReranker{extra: true},
+			results:  results("a", 0.9, "b", 0.5),
+			limit:    2,
+			want:     "a,b",
+			wantErr:  "returned 3 scores for 2 candidates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{config: &types.Config{Rerank: tt.config}}
+			e.SetReranker(tt.reranker)
+
+			got, err := e.rerank(context.Background(), "query", tt.results, tt.limit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("rerank: %v", err)
+			}
+
+			if order := ids(got); order != tt.want {
+				t.Errorf("order = %s, want %s", order, tt.want)
+			}
+			if tt.seen != "" {
+				if seen := strings.Join(tt.reranker.seen, ","); seen != tt.seen {
+					t.Errorf("reranker saw %s, want %s", seen, tt.seen)
+				}
+			}
+			if tt.topScore != 0 && math.Abs(got[0].Score-tt.topScore) > 1e-9 {
+				t.Errorf("top score = %f, want %f", got[0].Score, tt.topScore)
+			}
+		})
+	}
+}
+
+func TestRerankExplanation(t *testing.T) {
+	e := &Engine{config: &types.Config{}}
+	e.SetReranker(&fakeReranker{scores: map[string]float64{"a": 0.5}})
+
+	in := results("a", 0.8)
+	in[0].Explanation = &types.Explanation{Factors: []types.ScoreFactor{{Name: "semantic", Value: 0.8, Contribution: 0.8}}}
+
+	got, err := e.rerank(context.Background(), "query", in, 1)
+	if err != nil {
+		t.Fatalf("rerank: %v", err)
+	}
+
+	factors := got[0].Explanation.Factors
+	if len(factors) != 2 || factors[1].Name != "rerank" {
+		t.Fatalf("factors = %+v, want semantic then rerank", factors)
+	}
+	var sum float64
+	for _, f := range factors {
+		sum += f.Contribution
+	}
+	if math.Abs(sum-got[0].Score) > 1e-9 {
+		t.Errorf("contributions add up to %f, score is %f", sum, got[0].Score)
+	}
+}
+
+func TestHeuristicReranker(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		candidate types.Memory
+		want      float64
+	}{
+		{
+			name:      "no shared words",
+			query:     "database migrations",
+			candidate: types.Memory{Content: "use tabs"},
+			want:      0,
+		},
+		{
+			name:      "share of query words",
+			query:     "database migrations",
+			candidate: types.Memory{Content: "the database is sqlite"},
+			want:      0.3,
+		},
+		{
+			name:      "words in tags and topic key count",
+			query:     "database migrations",
+			candidate: types.Memory{Content: "see docs", TopicKey: "migrations", Tags: []string{"database"}},
+			want:      0.6,
+		},
+		{
+			name:      "verbatim query",
+			query:     "Database  Migrations",
+			candidate: types.Memory{Content: "how database migrations run"},
+			want:      0.85,
+		},
+		{
+			name:      "error memory for an error query",
+			query:     "connection refused",
+			candidate: types.Memory{Content: "connection refused: start the server", Type: types.TypeError},
+			want:      1,
+		},
+		{
+			name:      "error query alone earns no error bonus",
+			query:     "connection refused",
+			candidate: types.Memory{Content: "connection refused: start the server", Type: types.TypeGeneral},
+			want:      0.85,
+		},
+	}
+
+	h := &HeuristicReranker{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores, err := h.Rerank(context.Background(), tt.query, []*types.Memory{&tt.candidate})
+			if err != nil {
+				t.Fatalf("Rerank: %v", err)
+			}
+			if math.Abs(scores[0]-tt.want) > 1e-9 {
+				t.Errorf("score = %f, want %f", scores[0], tt.want)
+			}
+		})
+	}
+}
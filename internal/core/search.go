@@ -0,0 +1,87 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Search runs a keyword (BM25) search across all stores. Free text matches
+// memories containing any of its words; with advanced, the query uses the
+// syntax of db.ParseFTSQuery (phrases, prefixes, exclusions, OR and
+// topic_key:/tags: fields). Scores are relative to the best match.
+func (e *Engine) Search(query string, advanced bool, opts types.RecallOptions) ([]types.SearchResult, error) {
+	ftsQuery := db.FreeTextQuery(query)
+	if advanced {
+		var err error
+		if ftsQuery, err = db.ParseFTSQuery(query); err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+	}
+	if ftsQuery == "" {
+		return nil, fmt.Errorf("query has no searchable words")
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = 10
+	}
+
+	var results []types.SearchResult
+	for _, l := range e.layers {
+		matches, err := l.db.FTSSearch(ftsQuery, opts.Limit, opts)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: full-text search failed: %w", l.name, err)
+		}
+		for _, match := range matches {
+			memory, err := l.db.GetMemory(match.MemoryID)
+			if err != nil || memory == nil {
+				continue
+			}
+			memory.Store = l.name
+			results = append(results, types.SearchResult{
+				Memory:    *memory,
+				Score:     match.Score * l.weight,
+				MatchType: MatchKeyword,
+			})
+		}
+	}
+
+	results = mergeResults(results)
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+
+	if len(results) > 0 && results[0].Score > 0 {
+		best := results[0].Score
+		for i := range results {
+			results[i].Score /= best
+		}
+	}
+
+	return results, nil
+}
+
+// mergeResults sorts results by score and drops all but the best-scoring
+// copy of a memory that lives in several stores (e.g. after an import)
+func mergeResults(results []types.SearchResult) []types.SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	seen := make(map[string]bool)
+	merged := results[:0]
+	for _, r := range results {
+		key := r.Memory.ID
+		if r.Memory.TopicKey != "" {
+			key = "topic:" + r.Memory.TopicKey
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// ProjectStore is the name of the store at Config.DBPath
+const ProjectStore = "project"
+
+// layer is one opened memory store
+type layer struct {
+	name     string
+	db       *db.DB
+	readOnly bool
+	weight   float64
+	syncDir  string // Directory mirrored by Sync
+
+	keywordOnly atomic.Bool // Read-only with vectors from another model: skip vector search
+}
+
+// openLayers opens the project store and every configured extra store, in
+// the order recall should search them
+func openLayers(cfg *types.Config) ([]*layer, error) {
+	var layers []*layer
+	seen := make(map[string]bool)
+	for _, sc := range storeConfigs(cfg) {
+		if sc.Name == "" {
+			closeLayers(layers)
+			return nil, fmt.Errorf("store with path %s has no name", sc.Path)
+		}
+		if seen[sc.Name] {
+			closeLayers(layers)
+			return nil, fmt.Errorf("duplicate store name: %s", sc.Name)
+		}
+		seen[sc.Name] = true
+
+		l, err := openLayer(sc)
+		if err != nil {
+			closeLayers(layers)
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+
+	return layers, nil
+}
+
+// storeConfigs lists the project store and every configured extra store
+func storeConfigs(cfg *types.Config) []types.StoreConfig {
+	return append([]types.StoreConfig{{Name: ProjectStore, Path: cfg.DBPath}}, cfg.Stores...)
+}
+
+// FindStore returns the configuration of the named store, or of the default
+// store if name is empty
+func FindStore(cfg *types.Config, name string) (types.StoreConfig, error) {
+	if name == "" {
+		name = cfg.DefaultStore
+	}
+	if name == "" {
+		name = ProjectStore
+	}
+	for _, sc := range storeConfigs(cfg) {
+		if sc.Name == name {
+			return sc, nil
+		}
+	}
+	return types.StoreConfig{}, fmt.Errorf("unknown store: %s", name)
+}
+
+func openLayer(sc types.StoreConfig) (*layer, error) {
+	var database *db.DB
+	if sc.ReadOnly {
+		// Opened as is: no directory, migration or index is created
+		var err error
+		if database, err = db.OpenReadOnly(sc.Path); err != nil {
+			return nil, fmt.Errorf("failed to open read-only store %s: %w", sc.Name, err)
+		}
+	} else {
+		// Ensure data directory exists
+		if err := os.MkdirAll(filepath.Dir(sc.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory for store %s: %w", sc.Name, err)
+		}
+
+		var err error
+		if database, err = db.New(sc.Path); err != nil {
+			return nil, fmt.Errorf("failed to initialize store %s: %w", sc.Name, err)
+		}
+	}
+
+	weight := sc.Weight
+	if weight <= 0 {
+		weight = 1.0
+	}
+
+	syncDir := sc.SyncDir
+	if syncDir == "" {
+		syncDir = filepath.Join(filepath.Dir(sc.Path), "memories")
+	}
+
+	return &layer{
+		name:     sc.Name,
+		db:       database,
+		readOnly: sc.ReadOnly,
+		weight:   weight,
+		syncDir:  syncDir,
+	}, nil
+}
+
+func closeLayers(layers []*layer) error {
+	var firstErr error
+	for _, l := range layers {
+		if err := l.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// initVectorIndex creates or checks the layer's vector index. A read-only
+// layer built with another model can't be re-embedded, so it is recalled by
+// keyword only instead of failing.
+func (l *layer) initVectorIndex(model string, dimensions int) error {
+	if !l.readOnly {
+		return l.db.InitVectorIndex(model, dimensions)
+	}
+
+	err := l.db.CheckVectorIndex(model, dimensions)
+	var mismatch *db.EmbeddingMismatchError
+	var interrupted *db.ReembedInterruptedError
+	if errors.As(err, &mismatch) || errors.As(err, &interrupted) {
+		if !l.keywordOnly.Swap(true) {
+			fmt.Fprintf(os.Stderr, "warning: read-only store %s: %v; searching it by keyword only\n", l.name, err)
+		}
+		return nil
+	}
+	return err
+}
+
+// layer returns the store with the given name
+func (e *Engine) layer(name string) (*layer, error) {
+	for _, l := range e.layers {
+		if l.name == name {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown store: %s", name)
+}
+
+// locate finds the store holding a memory. The memory is nil if no store has it.
+func (e *Engine) locate(id string) (*layer, *types.Memory, error) {
+	for _, l := range e.layers {
+		memory, err := l.db.GetMemory(id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("store %s: %w", l.name, err)
+		}
+		if memory != nil {
+			memory.Store = l.name
+			return l, memory, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// locateWritable is like locate but fails if the memory is missing or its
+// store is read-only
+func (e *Engine) locateWritable(id string) (*layer, *types.Memory, error) {
+	l, memory, err := e.locate(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if memory == nil {
+		return nil, nil, fmt.Errorf("memory not found: %s", id)
+	}
+	if l.readOnly {
+		return nil, nil, fmt.Errorf("memory %s is in read-only store %s", id, l.name)
+	}
+	return l, memory, nil
+}
+
+// StoreInfo describes a configured store
+type StoreInfo struct {
+	Name     string `json:"name"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+	Default  bool   `json:"default,omitempty"`
+}
+
+// Stores returns the configured stores in search order
+func (e *Engine) Stores() []StoreInfo {
+	infos := make([]StoreInfo, len(e.layers))
+	for i, l := range e.layers {
+		infos[i] = StoreInfo{Name: l.name, ReadOnly: l.readOnly, Default: l.db == e.db}
+	}
+	return infos
+}
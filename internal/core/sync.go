@@ -0,0 +1,474 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/constantino-dev/cortex/internal/mdstore"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// SyncOptions configures a sync between a store and its Markdown mirror
+type SyncOptions struct {
+	Store     string // Store to sync (default: the default store)
+	Dir       string // Mirror directory (default: the store's sync directory)
+	Rebuild   bool   // Make the store match the files exactly and re-embed every memory
+	BatchSize int    // Memories per embedding request
+}
+
+// SyncStats counts what a sync changed
+type SyncStats struct {
+	Dir       string `json:"dir"`
+	Created   int    `json:"created"`   // Memories created from new files
+	Updated   int    `json:"updated"`   // Memories updated from changed files
+	Deleted   int    `json:"deleted"`   // Memories whose file was removed
+	Kept      int    `json:"kept"`      // Local changes not yet written to the files
+	Conflicts int    `json:"conflicts"` // Memories changed on both sides; the newer one won
+	Relations int    `json:"relations"` // Relations added or removed from files
+	Embedded  int    `json:"embedded"`  // Memories embedded
+	Written   int    `json:"written"`   // Files written
+	Removed   int    `json:"removed"`   // Files removed
+}
+
+// Sync brings a store and its Markdown mirror in line: changes in the files
+// (typically pulled from git) are applied to the store first, then the files
+// are rewritten from the store
+func (e *Engine) Sync(ctx context.Context, opts SyncOptions) (SyncStats, error) {
+	stats, err := e.SyncFromFiles(ctx, opts)
+	if err != nil {
+		return stats, err
+	}
+
+	out, err := e.SyncToFiles(opts)
+	stats.Written, stats.Removed = out.Written, out.Removed
+	return stats, err
+}
+
+// SyncFromFiles applies the Markdown mirror to the store. Each side is
+// compared with the state recorded at the last sync: files changed since then
+// update (or create) their memory, recording a revision, and files removed
+// since then delete it. Memories changed only locally are kept for the next
+// SyncToFiles. When both sides changed, the newer one wins. With Rebuild the
+// files win every time, every memory is re-embedded and the full-text index
+// is rebuilt.
+func (e *Engine) SyncFromFiles(ctx context.Context, opts SyncOptions) (SyncStats, error) {
+	l, dir, err := e.syncTarget(opts)
+	stats := SyncStats{Dir: dir}
+	if err != nil {
+		return stats, err
+	}
+	if l.readOnly {
+		return stats, fmt.Errorf("store %s is read-only", l.name)
+	}
+
+	files, err := readMirror(dir)
+	if err != nil {
+		return stats, err
+	}
+
+	local, err := e.mirrorDocs(l)
+	if err != nil {
+		return stats, err
+	}
+	current := make(map[string]*mdstore.Document, len(local))
+	for _, doc := range local {
+		current[doc.Memory.ID] = doc
+	}
+
+	base := make(map[string]string)
+	if !opts.Rebuild {
+		if base, err = l.db.GetSyncState(dir); err != nil {
+			return stats, fmt.Errorf("failed to read sync state: %w", err)
+		}
+	}
+
+	var (
+		toEmbed []*types.Memory
+		applied []*mdstore.Document // Files whose relations replace the store's
+	)
+
+	for _, file := range files {
+		id := file.Memory.ID
+		cur := current[id]
+		delete(current, id)
+
+		fileHash := hashDoc(file)
+		fileChanged := fileHash != base[id]
+
+		if cur == nil {
+			if !fileChanged {
+				// Deleted locally since the last sync; SyncToFiles removes the file
+				continue
+			}
+
+			m := file.Memory
+			if err := l.db.SaveMemory(&m); err != nil {
+				return stats, fmt.Errorf("failed to save %s: %w", id, err)
+			}
+			if m.TopicKey != "" {
+				if err := e.saveRevision(l.db, &m, "sync"); err != nil {
+					return stats, fmt.Errorf("failed to record revision: %w", err)
+				}
+			}
+			toEmbed = append(toEmbed, &m)
+			applied = append(applied, file)
+			stats.Created++
+			continue
+		}
+
+		curHash := hashDoc(cur)
+		if curHash == fileHash {
+			stale, err := e.needsEmbedding(l, &cur.Memory)
+			if err != nil {
+				return stats, err
+			}
+			if opts.Rebuild || stale {
+				toEmbed = append(toEmbed, &cur.Memory)
+			}
+			continue
+		}
+
+		localChanged := !opts.Rebuild && curHash != base[id]
+		if localChanged && !fileChanged {
+			stats.Kept++
+			continue
+		}
+		if localChanged {
+			stats.Conflicts++
+			if cur.Memory.UpdatedAt.After(file.Memory.UpdatedAt) {
+				fmt.Fprintf(os.Stderr, "warning: %s changed in the store and in its file; keeping the newer store version\n", id)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "warning: %s changed in the store and in its file; keeping the newer file (see 'cortex history')\n", id)
+		}
+
+		applied = append(applied, file)
+		if sameMemory(&cur.Memory, &file.Memory) {
+			// Only the relations differ
+			continue
+		}
+
+		if err := e.ensureBaseRevision(l.db, &cur.Memory); err != nil {
+			return stats, fmt.Errorf("failed to record revision: %w", err)
+		}
+		m := file.Memory
+		m.AccessCnt = cur.Memory.AccessCnt
+		if err := l.db.SaveMemory(&m); err != nil {
+			return stats, fmt.Errorf("failed to update %s: %w", id, err)
+		}
+		if err := e.saveRevision(l.db, &m, "sync"); err != nil {
+			return stats, fmt.Errorf("failed to record revision: %w", err)
+		}
+		if opts.Rebuild || m.Content != cur.Memory.Content {
+			toEmbed = append(toEmbed, &m)
+		}
+		stats.Updated++
+	}
+
+	// Whatever is left has no file: it was either removed from the files or
+	// never written to them
+	for id, cur := range current {
+		if !opts.Rebuild && (base[id] == "" || hashDoc(cur) != base[id]) {
+			stats.Kept++
+			continue
+		}
+		if err := l.db.DeleteMemory(id); err != nil {
+			return stats, fmt.Errorf("failed to delete %s: %w", id, err)
+		}
+		stats.Deleted++
+	}
+
+	// Relations are applied once every memory they may point at exists
+	for _, file := range applied {
+		n, err := e.syncRelations(l, file)
+		stats.Relations += n
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	n, err := e.embedMemories(ctx, l.db, toEmbed, opts.BatchSize)
+	stats.Embedded += n
+	if err != nil {
+		return stats, err
+	}
+
+	if opts.Rebuild {
+		if err := l.db.RebuildFTS(); err != nil {
+			return stats, fmt.Errorf("failed to rebuild full-text index: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// SyncToFiles writes one Markdown file per memory, with its outgoing
+// relations, removes the files of deleted memories and records the result as
+// the state of the last sync. Files whose content is unchanged are left
+// alone, so git only sees real changes.
+func (e *Engine) SyncToFiles(opts SyncOptions) (SyncStats, error) {
+	l, dir, err := e.syncTarget(opts)
+	stats := SyncStats{Dir: dir}
+	if err != nil {
+		return stats, err
+	}
+
+	docs, err := e.mirrorDocs(l)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return stats, fmt.Errorf("failed to create sync directory: %w", err)
+	}
+
+	written := make(map[string]bool, len(docs))
+	state := make(map[string]string, len(docs))
+	for _, doc := range docs {
+		rel := mdstore.PathFor(&doc.Memory)
+		if written[rel] {
+			rel = doc.Memory.ID + ".md"
+		}
+		written[rel] = true
+
+		data := mdstore.Marshal(doc)
+		state[doc.Memory.ID] = hashBytes(data)
+
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return stats, fmt.Errorf("failed to create directory for %s: %w", rel, err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return stats, fmt.Errorf("failed to write %s: %w", rel, err)
+		}
+		stats.Written++
+	}
+
+	// Remove memory files that no longer belong to a memory; leave other files alone
+	err = walkMirror(dir, func(path, rel string, doc *mdstore.Document) error {
+		if doc == nil || written[rel] {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+		stats.Removed++
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	if err := l.db.SetSyncState(dir, state); err != nil {
+		return stats, fmt.Errorf("failed to record sync state: %w", err)
+	}
+
+	return stats, nil
+}
+
+// syncTarget resolves the store and directory a sync works on. The directory
+// is made absolute because it keys the recorded sync state.
+func (e *Engine) syncTarget(opts SyncOptions) (*layer, string, error) {
+	var l *layer
+	if opts.Store != "" {
+		var err error
+		if l, err = e.layer(opts.Store); err != nil {
+			return nil, "", err
+		}
+	} else {
+		for _, candidate := range e.layers {
+			if candidate.db == e.db {
+				l = candidate
+			}
+		}
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = l.syncDir
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	return l, dir, nil
+}
+
+// mirrorDocs returns every memory of a store with its outgoing relations,
+// oldest first so that a memory keeps its file name when a newer one
+// collides with it
+func (e *Engine) mirrorDocs(l *layer) ([]*mdstore.Document, error) {
+	memories, err := l.db.ListMemories(types.RecallOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+	sort.Slice(memories, func(i, j int) bool {
+		if !memories[i].CreatedAt.Equal(memories[j].CreatedAt) {
+			return memories[i].CreatedAt.Before(memories[j].CreatedAt)
+		}
+		return memories[i].ID < memories[j].ID
+	})
+
+	relations, err := l.db.ListRelations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations: %w", err)
+	}
+	sort.Slice(relations, func(i, j int) bool {
+		if !relations[i].CreatedAt.Equal(relations[j].CreatedAt) {
+			return relations[i].CreatedAt.Before(relations[j].CreatedAt)
+		}
+		return relations[i].ID < relations[j].ID
+	})
+	outgoing := make(map[string][]types.Relation)
+	for _, r := range relations {
+		outgoing[r.FromID] = append(outgoing[r.FromID], *r)
+	}
+
+	docs := make([]*mdstore.Document, len(memories))
+	for i, m := range memories {
+		docs[i] = &mdstore.Document{Memory: *m, Relations: outgoing[m.ID]}
+	}
+	return docs, nil
+}
+
+// needsEmbedding reports whether a memory lacks a vector from the current model
+func (e *Engine) needsEmbedding(l *layer, m *types.Memory) (bool, error) {
+	vector, model, err := l.db.GetEmbeddingWithModel(m.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to read embedding of %s: %w", m.ID, err)
+	}
+	return vector == nil || model != e.embedder.Model(), nil
+}
+
+// syncRelations makes a memory's outgoing relations match its file.
+// Relations to memories that do not exist are skipped with a warning.
+func (e *Engine) syncRelations(l *layer, file *mdstore.Document) (int, error) {
+	existing, err := l.db.GetRelationsFrom(file.Memory.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read relations of %s: %w", file.Memory.ID, err)
+	}
+
+	changed := 0
+	inFile := make(map[string]bool, len(file.Relations))
+	for _, r := range file.Relations {
+		inFile[r.ID] = true
+	}
+	inStore := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		inStore[r.ID] = true
+		if inFile[r.ID] {
+			continue
+		}
+		if err := l.db.DeleteRelation(r.ID); err != nil {
+			return changed, fmt.Errorf("failed to delete relation %s: %w", r.ID, err)
+		}
+		changed++
+	}
+
+	for _, r := range file.Relations {
+		if inStore[r.ID] {
+			continue
+		}
+		target, err := l.db.GetMemory(r.ToID)
+		if err != nil {
+			return changed, err
+		}
+		if target == nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping relation %s of %s: memory %s not found\n", r.ID, file.Memory.ID, r.ToID)
+			continue
+		}
+		rel := r
+		if err := l.db.SaveRelation(&rel); err != nil {
+			return changed, fmt.Errorf("failed to save relation %s: %w", r.ID, err)
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+// readMirror parses every memory file under dir, sorted by path
+func readMirror(dir string) ([]*mdstore.Document, error) {
+	var docs []*mdstore.Document
+	seen := make(map[string]string)
+
+	err := walkMirror(dir, func(path, rel string, doc *mdstore.Document) error {
+		if doc == nil {
+			return nil
+		}
+		if other, ok := seen[doc.Memory.ID]; ok {
+			return fmt.Errorf("memory %s is in both %s and %s", doc.Memory.ID, other, rel)
+		}
+		seen[doc.Memory.ID] = rel
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// walkMirror calls fn for every .md file under dir with its path relative to
+// dir (slash-separated) and its parsed content, which is nil for Markdown
+// files that are not memories. A missing directory is an empty mirror.
+func walkMirror(dir string, fn func(path, rel string, doc *mdstore.Document) error) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		doc, err := mdstore.Unmarshal(data)
+		if errors.Is(err, mdstore.ErrNoFrontmatter) {
+			return fn(path, rel, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		return fn(path, rel, doc)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// sameMemory reports whether two memories serialize to the same file,
+// ignoring relations
+func sameMemory(a, b *types.Memory) bool {
+	return bytes.Equal(
+		mdstore.Marshal(&mdstore.Document{Memory: *a}),
+		mdstore.Marshal(&mdstore.Document{Memory: *b}),
+	)
+}
+
+// hashDoc returns the hash of a document's file content
+func hashDoc(doc *mdstore.Document) string {
+	return hashBytes(mdstore.Marshal(doc))
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
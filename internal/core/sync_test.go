@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/constantino-dev/cortex/internal/mdstore"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// TestSync syncs two stores through one mirror directory, like two clones of
+// a repository, and checks each sync's changes and the state it records
+func TestSync(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	t.Cleanup(func() { timeNow = time.Now })
+
+	dir := filepath.Join(t.TempDir(), "memories")
+	a := newTestEngine(t, newFakeEmbedder(), nil)
+	b := newTestEngine(t, newFakeEmbedder(), nil)
+	ctx := context.Background()
+
+	var engine, cgo *types.Memory
+	update := func(e *Engine, content string) {
+		if _, err := e.Update(ctx, engine.ID, types.UpdateOptions{Content: content}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+	syncNow := func(e *Engine) {
+		if _, err := e.Sync(ctx, SyncOptions{Dir: dir}); err != nil {
+			t.Fatalf("Sync: %v", err)
+		}
+	}
+	content := func(e *Engine) string {
+		m, err := e.Get(engine.ID)
+		if err != nil || m == nil {
+			t.Fatalf("Get: %v, %v", m, err)
+		}
+		return m.Content
+	}
+
+	steps := []struct {
+		name   string
+		side   *Engine
+		change func() // Local change made before the sync
+		want   SyncStats
+		check  func(t *testing.T)
+	}{
+		{
+			name: "first sync writes the local memories",
+			side: a,
+			change: func() {
+				engine = store(t, a, "we use sqlite for storage", types.StoreOptions{TopicKey: "db/engine"}).Memory
+				cgo = store(t, a, "the build needs a cgo toolchain", types.StoreOptions{}).Memory
+				if _, err := a.Relate(engine.ID, cgo.ID, types.RelRequires, ""); err != nil {
+					t.Fatalf("Relate: %v", err)
+				}
+			},
+			want: SyncStats{Kept: 2, Written: 2},
+		},
+		{
+			name: "other store creates them from the files",
+			side: b,
+			want: SyncStats{Created: 2, Relations: 1, Embedded: 2},
+			check: func(t *testing.T) {
+				relations, err := b.GetRelations(engine.ID)
+				if err != nil || len(relations) != 1 || relations[0].ToID != cgo.ID || relations[0].Type != types.RelRequires {
+					t.Errorf("relations = %+v, %v, want engine requires cgo", relations, err)
+				}
+				for _, m := range []*types.Memory{engine, cgo} {
+					if stale, err := b.needsEmbedding(b.layers[0], m); err != nil || stale {
+						t.Errorf("%q not embedded: %v", m.Content, err)
+					}
+				}
+			},
+		},
+		{
+			name:   "local change is kept and written",
+			side:   b,
+			change: func() { update(b, "we use sqlite with WAL for storage") },
+			want:   SyncStats{Kept: 1, Written: 1},
+		},
+		{
+			name: "file change updates the store",
+			side: a,
+			want: SyncStats{Updated: 1, Embedded: 1},
+			check: func(t *testing.T) {
+				if got := content(a); got != "we use sqlite with WAL for storage" {
+					t.Errorf("content = %q, want the file's", got)
+				}
+			},
+		},
+		{
+			name: "local deletion removes the file",
+			side: b,
+			change: func() {
+				if err := b.Delete(cgo.ID); err != nil {
+					t.Fatalf("Delete: %v", err)
+				}
+			},
+			// The engine's relation went with it, a local change to its file
+			want: SyncStats{Kept: 1, Written: 1, Removed: 1},
+		},
+		{
+			name: "removed file deletes the memory and its relation",
+			side: a,
+			want: SyncStats{Deleted: 1},
+			check: func(t *testing.T) {
+				if m, err := a.Get(cgo.ID); err != nil || m != nil {
+					t.Errorf("deleted memory still found: %+v, %v", m, err)
+				}
+				if relations, err := a.GetRelations(engine.ID); err != nil || len(relations) != 0 {
+					t.Errorf("relations = %+v, %v, want none", relations, err)
+				}
+			},
+		},
+		{
+			name: "changed on both sides, the file is newer",
+			side: a,
+			change: func() {
+				update(a, "we use postgres")
+				update(b, "we use sqlite in WAL mode")
+				syncNow(b)
+			},
+			want: SyncStats{Updated: 1, Conflicts: 1, Embedded: 1},
+			check: func(t *testing.T) {
+				if got := content(a); got != "we use sqlite in WAL mode" {
+					t.Errorf("content = %q, want the newer file's", got)
+				}
+				revisions, err := a.History(engine.ID)
+				if err != nil || len(revisions) < 2 || revisions[len(revisions)-2].Content != "we use postgres" {
+					t.Errorf("the store's version is not kept in the history: %v", err)
+				}
+			},
+		},
+		{
+			name: "changed on both sides, the store is newer",
+			side: b,
+			change: func() {
+				update(a, "we use sqlite 3.45")
+				syncNow(a)
+				update(b, "we use sqlite 3.46")
+			},
+			want: SyncStats{Conflicts: 1, Written: 1},
+			check: func(t *testing.T) {
+				if got := content(b); got != "we use sqlite 3.46" {
+					t.Errorf("content = %q, want the newer store's", got)
+				}
+			},
+		},
+		{
+			name: "both stores agree",
+			side: a,
+			want: SyncStats{Updated: 1, Embedded: 1},
+			check: func(t *testing.T) {
+				if got := content(a); got != "we use sqlite 3.46" {
+					t.Errorf("content = %q, want the last change", got)
+				}
+			},
+		},
+	}
+
+	for _, s := range steps {
+		if s.change != nil {
+			s.change()
+		}
+
+		stats, err := s.side.Sync(ctx, SyncOptions{Dir: dir})
+		if err != nil {
+			t.Fatalf("%s: Sync: %v", s.name, err)
+		}
+		s.want.Dir = dir
+		if stats != s.want {
+			t.Errorf("%s: synced %+v, want %+v", s.name, stats, s.want)
+		}
+		if s.check != nil {
+			s.check(t)
+		}
+
+		// The recorded state is the hash of every file as written
+		files := make(map[string]string)
+		err = walkMirror(dir, func(path, rel string, doc *mdstore.Document) error {
+			data, err := os.ReadFile(path)
+			files[doc.Memory.ID] = hashBytes(data)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, err := s.side.db.GetSyncState(dir)
+		if err != nil {
+			t.Fatalf("GetSyncState: %v", err)
+		}
+		if !reflect.DeepEqual(state, files) {
+			t.Errorf("%s: sync state = %v, want the files' hashes %v", s.name, state, files)
+		}
+	}
+
+	// Another sync of either side changes nothing
+	for _, e := range []*Engine{a, b} {
+		stats, err := e.Sync(ctx, SyncOptions{Dir: dir})
+		if err != nil || stats != (SyncStats{Dir: dir}) {
+			t.Errorf("Sync after the stores agree = %+v, %v, want no changes", stats, err)
+		}
+	}
+}
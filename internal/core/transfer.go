@@ -0,0 +1,451 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/constantino-dev/cortex/internal/db"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Export format: JSON Lines, one types.ExportRecord per line, in this order:
+//
+//	{"kind":"header","header":{"format":"cortex-export","version":1,...}}
+//	{"kind":"memory","memory":{...}}        one per memory
+//	{"kind":"revision","revision":{...}}    one per revision, oldest first
+//	{"kind":"relation","relation":{...}}    only between exported memories
+//	{"kind":"embedding","embedding":{...}}  only with ExportOptions.Embeddings
+const (
+	exportFormat  = "cortex-export"
+	exportVersion = 1
+)
+
+// ExportOptions configures an export
+type ExportOptions struct {
+	Filter     types.RecallOptions // Which memories to export (zero value: all)
+	Embeddings bool                // Include vectors and their model name
+}
+
+// ExportStats counts what was written
+type ExportStats struct {
+	Memories   int `json:"memories"`
+	Revisions  int `json:"revisions"`
+	Relations  int `json:"relations"`
+	Embeddings int `json:"embeddings"`
+}
+
+// Export writes memories, revisions, relations and optionally embeddings as JSONL
+func (e *Engine) Export(w io.Writer, opts ExportOptions) (ExportStats, error) {
+	var stats ExportStats
+	enc := json.NewEncoder(w)
+
+	model, dims, err := e.db.EmbeddingModel()
+	if err != nil {
+		return stats, err
+	}
+
+	header := &types.ExportHeader{
+		Format:     exportFormat,
+		Version:    exportVersion,
+		ExportedAt: timeNow(),
+	}
+	if opts.Embeddings {
+		header.EmbeddingModel = model
+		header.EmbeddingDimensions = dims
+	}
+	if err := enc.Encode(types.ExportRecord{Kind: "header", Header: header}); err != nil {
+		return stats, err
+	}
+
+	filter := opts.Filter
+	filter.Limit = 0
+	memories, err := e.db.ListMemories(filter)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	exported := make(map[string]bool, len(memories))
+	for _, m := range memories {
+		if err := enc.Encode(types.ExportRecord{Kind: "memory", Memory: m}); err != nil {
+			return stats, err
+		}
+		exported[m.ID] = true
+		stats.Memories++
+	}
+
+	for _, m := range memories {
+		revisions, err := e.db.GetRevisions(m.ID)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read revisions of %s: %w", m.ID, err)
+		}
+		for _, r := range revisions {
+			if err := enc.Encode(types.ExportRecord{Kind: "revision", Revision: r}); err != nil {
+				return stats, err
+			}
+			stats.Revisions++
+		}
+	}
+
+	relations, err := e.db.ListRelations()
+	if err != nil {
+		return stats, fmt.Errorf("failed to list relations: %w", err)
+	}
+	for _, r := range relations {
+		if !exported[r.FromID] || !exported[r.ToID] {
+			continue
+		}
+		if err := enc.Encode(types.ExportRecord{Kind: "relation", Relation: r}); err != nil {
+			return stats, err
+		}
+		stats.Relations++
+	}
+
+	if opts.Embeddings {
+		for _, m := range memories {
+			vector, embModel, err := e.db.GetEmbeddingWithModel(m.ID)
+			if err != nil {
+				return stats, fmt.Errorf("failed to read embedding of %s: %w", m.ID, err)
+			}
+			if vector == nil {
+				continue
+			}
+			err = enc.Encode(types.ExportRecord{Kind: "embedding", Embedding: &types.ExportEmbedding{
+				MemoryID: m.ID,
+				Model:    embModel,
+				Vector:   vector,
+			}})
+			if err != nil {
+				return stats, err
+			}
+			stats.Embeddings++
+		}
+	}
+
+	return stats, nil
+}
+
+// ImportOptions configures an import
+type ImportOptions struct {
+	RemapIDs  bool // Assign fresh IDs instead of keeping the exported ones
+	BatchSize int  // Memories per embedding request when re-embedding
+}
+
+// ImportStats counts what was read and how it was applied
+type ImportStats struct {
+	Memories   int `json:"memories"`   // New memories created
+	Updated    int `json:"updated"`    // Existing memories (same topic key or ID) updated with newer content
+	Skipped    int `json:"skipped"`    // Matches of an existing memory that were not newer
+	Revisions  int `json:"revisions"`  // Revisions restored
+	Relations  int `json:"relations"`  // Relations created
+	Embeddings int `json:"embeddings"` // Vectors copied from the export
+	Reembedded int `json:"reembedded"` // Memories embedded with the current provider
+}
+
+// Import reads a JSONL export. Memories whose topic key already exists are
+// merged into the existing memory (taking the newer content), relations are
+// re-pointed to the resulting IDs, and vectors are reused only when they come
+// from the current embedding model; everything else is re-embedded.
+func (e *Engine) Import(ctx context.Context, r io.Reader, opts ImportOptions) (ImportStats, error) {
+	var stats ImportStats
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultReembedBatchSize
+	}
+
+	var (
+		memories   []*types.Memory
+		revisions  []*types.Revision
+		relations  []*types.Relation
+		embeddings = make(map[string]*types.ExportEmbedding)
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec types.ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return stats, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+
+		switch {
+		case rec.Kind == "header" && rec.Header != nil:
+			if rec.Header.Format != exportFormat {
+				return stats, fmt.Errorf("line %d: not a cortex export (format %q)", line, rec.Header.Format)
+			}
+			if rec.Header.Version > exportVersion {
+				return stats, fmt.Errorf("export version %d is newer than this version of cortex supports (%d)", rec.Header.Version, exportVersion)
+			}
+		case rec.Kind == "memory" && rec.Memory != nil:
+			memories = append(memories, rec.Memory)
+		case rec.Kind == "revision" && rec.Revision != nil:
+			revisions = append(revisions, rec.Revision)
+		case rec.Kind == "relation" && rec.Relation != nil:
+			relations = append(relations, rec.Relation)
+		case rec.Kind == "embedding" && rec.Embedding != nil:
+			embeddings[rec.Embedding.MemoryID] = rec.Embedding
+		default:
+			return stats, fmt.Errorf("line %d: unknown record kind %q", line, rec.Kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+
+	dims, err := e.importDimensions()
+	if err != nil {
+		return stats, err
+	}
+
+	// Memories: map every exported ID to the ID it has in this store
+	idMap := make(map[string]string, len(memories))
+	created := make(map[string]bool)
+	var toEmbed []*types.Memory
+
+	for _, m := range memories {
+		sourceID := m.ID
+
+		existing, err := e.findImportTarget(m, opts.RemapIDs)
+		if err != nil {
+			return stats, err
+		}
+		if existing != nil {
+			idMap[sourceID] = existing.ID
+			if !m.UpdatedAt.After(existing.UpdatedAt) {
+				stats.Skipped++
+				continue
+			}
+
+			if err := e.ensureBaseRevision(e.db, existing); err != nil {
+				return stats, err
+			}
+			existing.Content = m.Content
+			existing.Type = m.Type
+			existing.Tags = m.Tags
+			existing.Trust = m.Trust
+			existing.UpdatedAt = m.UpdatedAt
+			if err := e.db.SaveMemory(existing); err != nil {
+				return stats, fmt.Errorf("failed to update %s: %w", existing.ID, err)
+			}
+			if err := e.saveRevision(e.db, existing, "import"); err != nil {
+				return stats, err
+			}
+			toEmbed = append(toEmbed, existing)
+			stats.Updated++
+			continue
+		}
+
+		imported := *m
+		if opts.RemapIDs {
+			imported.ID = generateID()
+		}
+		if imported.Type == "" {
+			imported.Type = types.TypeGeneral
+		}
+		if imported.Trust == "" {
+			imported.Trust = types.TrustProposed
+		}
+		if err := e.db.SaveMemory(&imported); err != nil {
+			return stats, fmt.Errorf("failed to save %s: %w", sourceID, err)
+		}
+
+		idMap[sourceID] = imported.ID
+		created[imported.ID] = true
+		stats.Memories++
+
+		if emb := embeddings[sourceID]; emb != nil && usableEmbedding(emb, e.embedder.Model(), dims) {
+			if dims == 0 {
+				// Neither the store nor the provider knows the dimension yet:
+				// the model's own vectors tell it
+				if err := e.ensureVectorIndex(len(emb.Vector)); err != nil {
+					return stats, err
+				}
+				dims = len(emb.Vector)
+			}
+			if err := e.db.SaveEmbedding(imported.ID, emb.Vector, emb.Model); err != nil {
+				return stats, fmt.Errorf("failed to save embedding for %s: %w", imported.ID, err)
+			}
+			stats.Embeddings++
+		} else {
+			toEmbed = append(toEmbed, &imported)
+		}
+	}
+
+	// Revisions only apply to newly created memories; merged ones keep their own history
+	for _, r := range revisions {
+		id, ok := idMap[r.MemoryID]
+		if !ok || !created[id] {
+			continue
+		}
+		rev := *r
+		rev.MemoryID = id
+		if _, err := e.db.SaveRevision(&rev); err != nil {
+			return stats, fmt.Errorf("failed to save revision of %s: %w", id, err)
+		}
+		stats.Revisions++
+	}
+
+	for _, r := range relations {
+		fromID, okFrom := idMap[r.FromID]
+		toID, okTo := idMap[r.ToID]
+		if !okFrom || !okTo {
+			continue
+		}
+
+		exists, err := e.hasRelation(fromID, toID, r.Type)
+		if err != nil {
+			return stats, err
+		}
+		if exists {
+			continue
+		}
+
+		rel := *r
+		rel.FromID, rel.ToID = fromID, toID
+		if opts.RemapIDs || rel.ID == "" {
+			rel.ID = generateID()
+		}
+		if err := e.db.SaveRelation(&rel); err != nil {
+			// The exported ID may already be taken in this store
+			rel.ID = generateID()
+			if err := e.db.SaveRelation(&rel); err != nil {
+				return stats, fmt.Errorf("failed to save relation: %w", err)
+			}
+		}
+		stats.Relations++
+	}
+
+	// Re-embed everything that did not come with a vector from the current model
+	n, err := e.embedMemories(ctx, e.db, toEmbed, opts.BatchSize)
+	stats.Reembedded += n
+	if err != nil {
+		return stats, fmt.Errorf("%w (run 'cortex reembed' to finish)", err)
+	}
+
+	return stats, nil
+}
+
+// embedMemories embeds memories in batches and stores the vectors. Content
+// found in the embedding cache is not sent to the provider. It returns how
+// many were embedded before any error.
+func (e *Engine) embedMemories(ctx context.Context, store *db.DB, memories []*types.Memory, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultReembedBatchSize
+	}
+	model := e.embedder.Model()
+
+	done := 0
+	var uncached []*types.Memory
+	for _, m := range memories {
+		embedding, err := store.GetCachedEmbedding(model, hashBytes([]byte(m.Content)))
+		if err != nil || embedding == nil {
+			uncached = append(uncached, m)
+			continue
+		}
+		if err := e.ensureVectorIndex(len(embedding)); err != nil {
+			return done, err
+		}
+		if err := store.SaveEmbedding(m.ID, embedding, model); err != nil {
+			return done, fmt.Errorf("failed to save embedding for %s: %w", m.ID, err)
+		}
+		done++
+	}
+	store.Count(db.CacheHits, done)
+	memories = uncached
+
+	for start := 0; start < len(memories); start += batchSize {
+		end := start + batchSize
+		if end > len(memories) {
+			end = len(memories)
+		}
+		batch := memories[start:end]
+
+		texts := make([]string, len(batch))
+		for i, m := range batch {
+			texts[i] = m.Content
+		}
+
+		vectors, err := e.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			// Leave the rest to the retry queue
+			for _, m := range memories[start:] {
+				e.queueEmbedding(store, m.ID, err)
+			}
+			return done, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		if len(vectors) != len(batch) {
+			return done, fmt.Errorf("provider returned %d embeddings for %d memories", len(vectors), len(batch))
+		}
+		if err := e.ensureVectorIndex(len(vectors[0])); err != nil {
+			return done, err
+		}
+		store.Count(db.CacheMisses, len(batch))
+		for i, m := range batch {
+			if err := store.SaveEmbedding(m.ID, vectors[i], model); err != nil {
+				return done, fmt.Errorf("failed to save embedding for %s: %w", m.ID, err)
+			}
+			if err := store.CacheEmbedding(model, hashBytes([]byte(m.Content)), vectors[i]); err != nil {
+				return done, fmt.Errorf("failed to cache embedding for %s: %w", m.ID, err)
+			}
+		}
+		done += len(batch)
+	}
+
+	return done, nil
+}
+
+// findImportTarget returns the memory an imported one should be merged into:
+// the one with the same topic key or, when IDs are kept, the same ID
+func (e *Engine) findImportTarget(m *types.Memory, remapIDs bool) (*types.Memory, error) {
+	if m.TopicKey != "" {
+		existing, err := e.db.GetMemoryByTopicKey(m.TopicKey)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+	if remapIDs {
+		return nil, nil
+	}
+	return e.db.GetMemory(m.ID)
+}
+
+// importDimensions returns the length imported vectors must have: the
+// dimension recorded for the store's vector index, or the provider's if the
+// index doesn't exist yet (0 if the provider hasn't learned it either)
+func (e *Engine) importDimensions() (int, error) {
+	model, dims, err := e.db.EmbeddingModel()
+	if err != nil {
+		return 0, err
+	}
+	if model == e.embedder.Model() && dims > 0 {
+		return dims, nil
+	}
+	return e.embedder.Dimensions(), nil
+}
+
+// usableEmbedding reports whether an exported vector can be stored as-is:
+// it comes from model and has the given dimension, if known
+func usableEmbedding(emb *types.ExportEmbedding, model string, dims int) bool {
+	return emb.Model == model && len(emb.Vector) > 0 && (dims == 0 || len(emb.Vector) == dims)
+}
+
+// hasRelation reports whether a relation of the given type already links two memories
+func (e *Engine) hasRelation(fromID, toID string, relType types.RelationType) (bool, error) {
+	relations, err := e.db.GetRelationsFrom(fromID)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range relations {
+		if r.ToID == toID && r.Type == relType {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+func TestExportImportKeepsVectors(t *testing.T) {
+	source := newTestEngine(t, newFakeEmbedder(), nil)
+	validated := types.StoreOptions{Trust: types.TrustValidated}
+	a := store(t, source, "we use sqlite for storage", types.StoreOptions{TopicKey: "db/engine", Tags: []string{"db"}}).Memory
+	b := store(t, source, "run migrations when the engine starts", validated).Memory
+	if _, err := source.Relate(b.ID, a.ID, types.RelRequires, ""); err != nil {
+		t.Fatalf("Relate: %v", err)
+	}
+
+	var export bytes.Buffer
+	exported, err := source.Export(&export, ExportOptions{Embeddings: true})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if exported != (ExportStats{Memories: 2, Revisions: 1, Relations: 1, Embeddings: 2}) {
+		t.Fatalf("exported %+v", exported)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, path string) // Prepares the target store
+	}{
+		{
+			name: "new store",
+		},
+		{
+			name: "store with a vector index",
+			setup: func(t *testing.T, path string) {
+				prior, err := open(&types.Config{DBPath: path}, newFakeEmbedder(), true)
+				if err != nil {
+					t.Fatalf("open: %v", err)
+				}
+				store(t, prior, "tabs over spaces", validated)
+				prior.Close()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cortex.db")
+			if tt.setup != nil {
+				tt.setup(t, path)
+			}
+
+			// Like Ollama before its first embedding, and unreachable
+			embedder := newFakeEmbedder()
+			embedder.lazy = true
+			embedder.fail(errors.New("provider down"))
+			target := newTestEngine(t, embedder, func(cfg *types.Config) { cfg.DBPath = path })
+
+			stats, err := target.Import(context.Background(), bytes.NewReader(export.Bytes()), ImportOptions{})
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if stats != (ImportStats{Memories: 2, Revisions: 1, Relations: 1, Embeddings: 2}) {
+				t.Errorf("imported %+v, want 2 memories with their vectors", stats)
+			}
+			if n := embedder.count(); n != 0 {
+				t.Errorf("provider asked for %d embeddings, want none", n)
+			}
+
+			for _, m := range []*types.Memory{a, b} {
+				want, err := source.db.GetEmbedding(m.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := target.db.GetEmbedding(m.ID)
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("vector of %q = %v, %v, want the exported one", m.Content, got, err)
+				}
+
+				nearest, err := target.db.VectorSearch(want, 1, types.RecallOptions{})
+				if err != nil || len(nearest) != 1 || nearest[0].MemoryID != m.ID {
+					t.Errorf("VectorSearch for %q = %v, %v", m.Content, nearest, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Meta keys counting embedding cache lookups
+const (
+	CacheHits        = "cache_hits"         // Memory content found in the embedding cache
+	CacheMisses      = "cache_misses"       // Memory content that had to be embedded
+	QueryCacheHits   = "query_cache_hits"   // Recall queries found in the query cache
+	QueryCacheMisses = "query_cache_misses" // Recall queries that had to be embedded
+)
+
+// CacheCounters lists the cache counters reported by Stats
+var CacheCounters = []string{CacheHits, CacheMisses, QueryCacheHits, QueryCacheMisses}
+
+// GetCachedEmbedding returns the cached embedding of a text by model and
+// content hash, or nil if there is none
+func (db *DB) GetCachedEmbedding(model, hash string) ([]float32, error) {
+	var embBytes []byte
+	err := db.conn.QueryRow("SELECT embedding FROM embedding_cache WHERE model = ? AND hash = ?", model, hash).Scan(&embBytes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytesToFloat32(embBytes), nil
+}
+
+// CacheEmbedding stores the embedding of a text by model and content hash.
+// PruneEmbeddingCache keeps the table bounded.
+func (db *DB) CacheEmbedding(model, hash string, embedding []float32) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO embedding_cache (model, hash, embedding, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(model, hash) DO UPDATE SET
+			embedding = excluded.embedding,
+			created_at = excluded.created_at
+	`, model, hash, float32ToBytes(embedding), time.Now().Format(time.RFC3339))
+	return err
+}
+
+// PruneEmbeddingCache deletes the oldest cached embeddings beyond keep and
+// returns how many were deleted
+func (db *DB) PruneEmbeddingCache(keep int) (int, error) {
+	res, err := db.conn.Exec(`
+		DELETE FROM embedding_cache WHERE rowid IN (
+			SELECT rowid FROM embedding_cache
+			ORDER BY created_at DESC, rowid DESC
+			LIMIT -1 OFFSET ?
+		)
+	`, keep)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Count adds n to a counter in memory. Counting happens on hot paths, so the
+// counts are written to the meta table by FlushCounts (or Close) instead of
+// costing a write transaction each.
+func (db *DB) Count(key string, n int) {
+	db.countMu.Lock()
+	defer db.countMu.Unlock()
+
+	if db.counts == nil {
+		db.counts = make(map[string]int)
+	}
+	db.counts[key] += n
+}
+
+// counted returns the increments of a counter not yet flushed
+func (db *DB) counted(key string) int {
+	db.countMu.Lock()
+	defer db.countMu.Unlock()
+	return db.counts[key]
+}
+
+// FlushCounts adds the counted increments to the meta table in one transaction
+func (db *DB) FlushCounts() error {
+	db.countMu.Lock()
+	defer db.countMu.Unlock()
+
+	if len(db.counts) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	for key, n := range db.counts {
+		if _, err := tx.Exec(addMetaSQL, key, n); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	db.counts = nil
+	return nil
+}
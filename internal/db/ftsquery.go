@@ -0,0 +1,191 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ftsColumns are the columns of fts_memories a query may filter on
+var ftsColumns = map[string]bool{
+	"content":   true,
+	"topic_key": true,
+	"tags":      true,
+}
+
+// FreeTextQuery turns arbitrary text, such as an error message, into a safe
+// fts5 expression matching memories that contain any of its words. It
+// returns "" if the text has no searchable words.
+func FreeTextQuery(text string) string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range ftsWords(text) {
+		lower := strings.ToLower(word)
+		if seen[lower] {
+			continue
+		}
+		seen[lower] = true
+		terms = append(terms, quoteFTS(word))
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// ParseFTSQuery translates the advanced search syntax into an fts5
+// expression. Terms must all match unless joined by OR:
+//
+//	word         a word
+//	"two words"  a phrase
+//	pref*        words starting with pref
+//	-word        exclude memories containing word
+//	a OR b       either term
+//	field:term   search one field: content, topic_key or tags
+//
+// A field term can itself be a phrase or a prefix, e.g. topic_key:"react/hooks"*.
+func ParseFTSQuery(query string) (string, error) {
+	tokens, err := splitFTSQuery(query)
+	if err != nil {
+		return "", err
+	}
+
+	var groups [][]string // OR-groups, ANDed together
+	var excluded []string
+	joinNext := false
+
+	for i, tok := range tokens {
+		if tok == "OR" {
+			if len(groups) == 0 || joinNext || i == len(tokens)-1 {
+				return "", fmt.Errorf("OR must stand between two terms")
+			}
+			joinNext = true
+			continue
+		}
+
+		negate := strings.HasPrefix(tok, "-") && len(tok) > 1
+		if negate {
+			tok = tok[1:]
+		}
+
+		term, err := parseFTSTerm(tok)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case negate:
+			if joinNext {
+				return "", fmt.Errorf("an excluded term can't be part of OR")
+			}
+			excluded = append(excluded, term)
+		case joinNext:
+			groups[len(groups)-1] = append(groups[len(groups)-1], term)
+		default:
+			groups = append(groups, []string{term})
+		}
+		joinNext = false
+	}
+
+	if len(groups) == 0 {
+		return "", fmt.Errorf("query needs at least one term that is not excluded")
+	}
+
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		if len(g) == 1 {
+			parts[i] = g[0]
+		} else {
+			parts[i] = "(" + strings.Join(g, " OR ") + ")"
+		}
+	}
+	expr := strings.Join(parts, " AND ")
+
+	if len(excluded) > 0 {
+		expr = "(" + expr + ")"
+		for _, term := range excluded {
+			expr += " NOT " + term
+		}
+	}
+
+	return expr, nil
+}
+
+// splitFTSQuery splits a query on whitespace, keeping quoted phrases whole
+func splitFTSQuery(query string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	return tokens, nil
+}
+
+// parseFTSTerm translates one [field:]word, [field:]"phrase" or prefix*
+func parseFTSTerm(tok string) (string, error) {
+	column := ""
+	if i := strings.Index(tok, ":"); i > 0 && !strings.HasPrefix(tok, `"`) {
+		if !ftsColumns[tok[:i]] {
+			return "", fmt.Errorf("unknown field %q (use content, topic_key or tags)", tok[:i])
+		}
+		column, tok = tok[:i], tok[i+1:]
+	}
+
+	prefix := strings.HasSuffix(tok, "*")
+	tok = strings.TrimSuffix(tok, "*")
+
+	text := tok
+	if strings.HasPrefix(tok, `"`) {
+		if len(tok) < 2 || !strings.HasSuffix(tok, `"`) {
+			return "", fmt.Errorf("malformed phrase %s", tok)
+		}
+		text = tok[1 : len(tok)-1]
+	}
+
+	// The phrase holds the words fts5 would index, so punctuation such as
+	// the slashes of a topic key can't break the expression
+	words := ftsWords(text)
+	if len(words) == 0 {
+		return "", fmt.Errorf("term %q has nothing to search for", tok)
+	}
+
+	term := quoteFTS(strings.Join(words, " "))
+	if prefix {
+		term += "*"
+	}
+	if column != "" {
+		term = column + " : " + term
+	}
+	return term, nil
+}
+
+// ftsWords splits text into the words the unicode61 tokenizer indexes
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// quoteFTS quotes a string as an fts5 phrase
+func quoteFTS(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
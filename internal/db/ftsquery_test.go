@@ -0,0 +1,168 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// openSearchDB opens a migrated database holding a few memories to search
+func openSearchDB(t *testing.T) *DB {
+	t.Helper()
+	db := openTestDB(t)
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	now := time.Now()
+	memories := []*types.Memory{
+		{ID: "hooks", Content: "React hooks must be called at the top level", TopicKey: "react/hooks/rules", Tags: []string{"react", "hooks"}},
+		{ID: "classes", Content: "Class components have no hooks", TopicKey: "react/classes"},
+		{ID: "refused", Content: `dial tcp 127.0.0.1:5432: connect: connection refused (is "postgres" running?)`, TopicKey: "db/connect", Tags: []string{"postgres"}},
+		{ID: "cors", Content: "Enable CORS for the dev-server proxy", Tags: []string{"frontend"}},
+		{ID: "tabs", Content: "Use tabs, not spaces", TopicKey: "style/indent"},
+	}
+	for _, m := range memories {
+		m.Type = types.TypeGeneral
+		m.Trust = types.TrustProposed
+		m.CreatedAt, m.UpdatedAt = now, now
+		if err := db.SaveMemory(m); err != nil {
+			t.Fatalf("SaveMemory: %v", err)
+		}
+	}
+	return db
+}
+
+// searchIDs runs an fts5 expression and returns the sorted IDs it matches
+func searchIDs(t *testing.T, db *DB, expr string) string {
+	t.Helper()
+	results, err := db.FTSSearch(expr, 10, types.RecallOptions{})
+	if err != nil {
+		t.Fatalf("FTSSearch(%s): %v", expr, err)
+	}
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.MemoryID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func TestFreeTextQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // fts5 expression
+		rows string // Sorted IDs it matches
+	}{
+		{
+			name: "error message with quotes, colons and parens",
+			text: `connect: connection refused (is "postgres" running?)`,
+			want: `"connect" OR "connection" OR "refused" OR "is" OR "postgres" OR "running"`,
+			rows: "refused",
+		},
+		{
+			name: "operators are searched as words",
+			text: "tabs NOT spaces",
+			want: `"tabs" OR "NOT" OR "spaces"`,
+			rows: "tabs",
+		},
+		{
+			name: "repeated words once",
+			text: "Hooks hooks HOOKS",
+			want: `"Hooks"`,
+			rows: "classes,hooks",
+		},
+		{
+			name: "hyphenated word",
+			text: "dev-server",
+			want: `"dev" OR "server"`,
+			rows: "cors",
+		},
+		{
+			name: "no words",
+			text: `!!! -- ()`,
+			want: "",
+		},
+	}
+
+	db := openSearchDB(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FreeTextQuery(tt.text)
+			if got != tt.want {
+				t.Fatalf("FreeTextQuery(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			if rows := searchIDs(t, db, got); rows != tt.rows {
+				t.Errorf("matched %s, want %s", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestParseFTSQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string // fts5 expression
+		rows    string // Sorted IDs it matches
+		wantErr string
+	}{
+		{query: "hooks", want: `"hooks"`, rows: "classes,hooks"},
+		{query: "react hooks", want: `"react" AND "hooks"`, rows: "classes,hooks"},
+		{query: `"top level"`, want: `"top level"`, rows: "hooks"},
+		{query: "hoo*", want: `"hoo"*`, rows: "classes,hooks"},
+		{query: "react OR tabs", want: `("react" OR "tabs")`, rows: "classes,hooks,tabs"},
+		{query: "the -react", want: `("the") NOT "react"`, rows: "cors"},
+		{query: "dev-server", want: `"dev server"`, rows: "cors"},
+		{query: "(postgres)", want: `"postgres"`, rows: "refused"},
+		{query: `"127.0.0.1:5432"`, want: `"127 0 0 1 5432"`, rows: "refused"},
+		{query: `"is \"postgres\""`, want: `"is postgres"`, rows: "refused"},
+		{query: `topic_key:"react`, wantErr: "unterminated quote"},
+		{query: "NOT", want: `"NOT"`, rows: "tabs"},
+		{query: "AND NEAR", want: `"AND" AND "NEAR"`, rows: ""},
+		{query: "topic_key:hooks", want: `topic_key : "hooks"`, rows: "hooks"},
+		{query: `topic_key:"react/cla"*`, want: `topic_key : "react cla"*`, rows: "classes"},
+		{query: "topic_key:db/connect", want: `topic_key : "db connect"`, rows: "refused"},
+		{query: "tags:hooks", want: `tags : "hooks"`, rows: "hooks"},
+		{query: "tags:react OR tags:frontend", want: `(tags : "react" OR tags : "frontend")`, rows: "cors,hooks"},
+		{query: "content:react -tags:hooks", want: `(content : "react") NOT tags : "hooks"`, rows: ""},
+		{query: "127.0.0.1:5432", wantErr: "unknown field"},
+		{query: "author:me", wantErr: "unknown field"},
+		{query: "OR", wantErr: "OR must stand between two terms"},
+		{query: "tabs OR", wantErr: "OR must stand between two terms"},
+		{query: "tabs OR OR hooks", wantErr: "OR must stand between two terms"},
+		{query: "tabs OR -hooks", wantErr: "can't be part of OR"},
+		{query: "-tabs", wantErr: "at least one term"},
+		{query: "-", wantErr: "nothing to search for"},
+		{query: `"unterminated`, wantErr: "unterminated quote"},
+		{query: "   ", wantErr: "empty query"},
+	}
+
+	db := openSearchDB(t)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := ParseFTSQuery(tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseFTSQuery(%q) = %s, %v, want an error containing %q", tt.query, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFTSQuery(%q): %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseFTSQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+			if rows := searchIDs(t, db, got); rows != tt.rows {
+				t.Errorf("matched %s, want %s", rows, tt.rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Migration is one ordered, transactional change to the database schema.
+// Migrations are append-only: never edit or reorder one that has shipped,
+// add a new one instead.
+type Migration struct {
+	Version int                    `json:"version"`
+	Name    string                 `json:"name"`
+	Up      func(tx *sql.Tx) error `json:"-"`
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+// migrations lists every schema change in the order it must be applied
+var migrations = []Migration{
+	{Version: 1, Name: "initial schema", Up: migrateInitialSchema},
+	{Version: 2, Name: "meta table", Up: migrateMetaTable},
+	{Version: 3, Name: "memory revisions", Up: migrateMemoryRevisions},
+	{Version: 4, Name: "sync state", Up: migrateSyncState},
+	{Version: 5, Name: "vector metadata columns", Up: migrateVecMetadata},
+	{Version: 6, Name: "embedding cache", Up: migrateEmbeddingCache},
+	{Version: 7, Name: "pending embeddings", Up: migratePendingEmbeddings},
+}
+
+// LatestSchemaVersion returns the schema version this build expects
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureVersionTable creates the table that records applied migrations
+func (db *DB) ensureVersionTable() error {
+	_, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)
+	`)
+	return err
+}
+
+// appliedMigrations returns the applied migration versions and when they ran
+func (db *DB) appliedMigrations() (map[int]time.Time, error) {
+	exists, err := db.tableExists("schema_version")
+	if err != nil || !exists {
+		return map[int]time.Time{}, err
+	}
+
+	rows, err := db.conn.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedStr string
+		if err := rows.Scan(&version, &appliedStr); err != nil {
+			return nil, err
+		}
+		applied[version], _ = time.Parse(time.RFC3339, appliedStr)
+	}
+
+	return applied, rows.Err()
+}
+
+// SchemaVersion returns the highest applied migration version (0 for a new database)
+func (db *DB) SchemaVersion() (int, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// MigrationStatus returns every known migration and whether it has been applied
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		at, ok := applied[m.Version]
+		status[i] = MigrationStatus{Migration: m, Applied: ok, AppliedAt: at}
+	}
+	return status, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied yet
+func (db *DB) PendingMigrations() ([]Migration, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for v := range applied {
+		if v > LatestSchemaVersion() {
+			return nil, fmt.Errorf("database schema version %d is newer than this version of cortex supports (%d)", v, LatestSchemaVersion())
+		}
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies all pending migrations in order, each in its own
+// transaction, and returns the ones that were applied
+func (db *DB) Migrate() ([]Migration, error) {
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	if err := db.ensureVersionTable(); err != nil {
+		return nil, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		if err := db.applyMigration(m); err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+func (db *DB) applyMigration(m Migration) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Name, time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// tableExists reports whether a table (or virtual table) exists
+func (db *DB) tableExists(name string) (bool, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+// migrateInitialSchema creates the original schema. It uses IF NOT EXISTS so
+// that databases created before schema_version existed adopt it unchanged.
+func migrateInitialSchema(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	-- Memories table
+	CREATE TABLE IF NOT EXISTS memories (
+		id TEXT PRIMARY KEY,
+		content TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'general',
+		topic_key TEXT,
+		tags TEXT, -- JSON array
+		trust TEXT NOT NULL DEFAULT 'proposed',
+		metadata TEXT, -- JSON object
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		access_count INTEGER DEFAULT 0
+	);
+
+	-- Index for topic_key lookups and evolution
+	CREATE INDEX IF NOT EXISTS idx_memories_topic_key ON memories(topic_key);
+	CREATE INDEX IF NOT EXISTS idx_memories_type ON memories(type);
+	CREATE INDEX IF NOT EXISTS idx_memories_trust ON memories(trust);
+	CREATE INDEX IF NOT EXISTS idx_memories_created ON memories(created_at);
+
+	-- Relations table
+	CREATE TABLE IF NOT EXISTS relations (
+		id TEXT PRIMARY KEY,
+		from_id TEXT NOT NULL,
+		to_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		note TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (from_id) REFERENCES memories(id) ON DELETE CASCADE,
+		FOREIGN KEY (to_id) REFERENCES memories(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_id);
+	CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_id);
+	CREATE INDEX IF NOT EXISTS idx_relations_type ON relations(type);
+
+	-- Embeddings cache
+	CREATE TABLE IF NOT EXISTS embeddings (
+		memory_id TEXT PRIMARY KEY,
+		embedding BLOB NOT NULL, -- float32 array as blob
+		model TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (memory_id) REFERENCES memories(id) ON DELETE CASCADE
+	);
+
+	-- Full-text search for keyword matching
+	CREATE VIRTUAL TABLE IF NOT EXISTS fts_memories USING fts5(
+		content,
+		topic_key,
+		tags,
+		content=memories,
+		content_rowid=rowid
+	);
+
+	-- Triggers to keep FTS in sync
+	CREATE TRIGGER IF NOT EXISTS memories_ai AFTER INSERT ON memories BEGIN
+		INSERT INTO fts_memories(rowid, content, topic_key, tags)
+		VALUES (new.rowid, new.content, new.topic_key, new.tags);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS memories_ad AFTER DELETE ON memories BEGIN
+		INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
+		VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS memories_au AFTER UPDATE ON memories BEGIN
+		INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
+		VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
+		INSERT INTO fts_memories(rowid, content, topic_key, tags)
+		VALUES (new.rowid, new.content, new.topic_key, new.tags);
+	END;
+	`)
+	return err
+}
+
+// migrateMetaTable adds the key/value table describing the vector index
+func migrateMetaTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)
+	`)
+	return err
+}
+
+// migrateMemoryRevisions adds the revision history of topic-key updates
+func migrateMemoryRevisions(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS memory_revisions (
+		memory_id TEXT NOT NULL,
+		rev INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		type TEXT NOT NULL,
+		tags TEXT, -- JSON array
+		trust TEXT NOT NULL,
+		author TEXT,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (memory_id, rev),
+		FOREIGN KEY (memory_id) REFERENCES memories(id) ON DELETE CASCADE
+	)
+	`)
+	return err
+}
+
+// migrateSyncState adds the record of what each Markdown mirror held after
+// the last sync
+func migrateSyncState(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS sync_state (
+		dir TEXT NOT NULL,
+		memory_id TEXT NOT NULL,
+		hash TEXT NOT NULL, -- SHA-256 of the memory's file
+		PRIMARY KEY (dir, memory_id)
+	)
+	`)
+	return err
+}
+
+// migrateEmbeddingCache adds the cache of embeddings by model and content hash
+func migrateEmbeddingCache(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS embedding_cache (
+		model TEXT NOT NULL,
+		hash TEXT NOT NULL, -- SHA-256 of the embedded text
+		embedding BLOB NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (model, hash)
+	)
+	`)
+	return err
+}
+
+// migratePendingEmbeddings adds the queue of memories whose embedding failed,
+// starting with every memory that has none
+func migratePendingEmbeddings(tx *sql.Tx) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS pending_embeddings (
+			memory_id TEXT PRIMARY KEY,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt TEXT NOT NULL, -- UTC, RFC 3339
+			created_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_next ON pending_embeddings(next_attempt)`,
+		`INSERT OR IGNORE INTO pending_embeddings (memory_id, attempts, next_attempt, created_at)
+			SELECT m.id, 0, '` + now + `', '` + now + `'
+			FROM memories m LEFT JOIN embeddings e ON e.memory_id = m.id
+			WHERE e.memory_id IS NULL`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateVecMetadata rebuilds vec_memories with the project, type and trust
+// columns that let filters run inside the KNN query. Databases whose vector
+// table does not exist yet get it from InitVectorIndex.
+func migrateVecMetadata(tx *sql.Tx) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'vec_memories'").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	dimensions := legacyEmbeddingDimensions
+	var dimStr string
+	err := tx.QueryRow("SELECT value FROM meta WHERE key = ?", metaEmbeddingDimensions).Scan(&dimStr)
+	switch {
+	case err == nil:
+		if dimensions, err = strconv.Atoi(dimStr); err != nil {
+			return fmt.Errorf("invalid %s in meta table: %q", metaEmbeddingDimensions, dimStr)
+		}
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	// Vectors of deleted memories are dropped on the way
+	statements := []string{
+		"CREATE TEMP TABLE vec_backup AS SELECT memory_id, embedding FROM vec_memories",
+		"DROP TABLE vec_memories",
+		vecTableSQL(dimensions),
+		`INSERT INTO vec_memories (memory_id, embedding, project, type, trust)
+			SELECT b.memory_id, b.embedding, ` + vecProjectExpr + `, m.type, m.trust
+			FROM vec_backup b JOIN memories m ON m.id = b.memory_id`,
+		"DROP TABLE vec_backup",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// baselineSchema is the schema databases had before migrations were versioned
+const baselineSchema = `
+CREATE TABLE IF NOT EXISTS memories (
+	id TEXT PRIMARY KEY,
+	content TEXT NOT NULL,
+	type TEXT NOT NULL DEFAULT 'general',
+	topic_key TEXT,
+	tags TEXT,
+	trust TEXT NOT NULL DEFAULT 'proposed',
+	metadata TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	access_count INTEGER DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_memories_topic_key ON memories(topic_key);
+CREATE INDEX IF NOT EXISTS idx_memories_type ON memories(type);
+CREATE INDEX IF NOT EXISTS idx_memories_trust ON memories(trust);
+CREATE INDEX IF NOT EXISTS idx_memories_created ON memories(created_at);
+
+CREATE TABLE IF NOT EXISTS relations (
+	id TEXT PRIMARY KEY,
+	from_id TEXT NOT NULL,
+	to_id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	note TEXT,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (from_id) REFERENCES memories(id) ON DELETE CASCADE,
+	FOREIGN KEY (to_id) REFERENCES memories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_id);
+CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_id);
+CREATE INDEX IF NOT EXISTS idx_relations_type ON relations(type);
+
+CREATE TABLE IF NOT EXISTS embeddings (
+	memory_id TEXT PRIMARY KEY,
+	embedding BLOB NOT NULL,
+	model TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (memory_id) REFERENCES memories(id) ON DELETE CASCADE
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS vec_memories USING vec0(
+	memory_id TEXT PRIMARY KEY,
+	embedding float[1536]
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS fts_memories USING fts5(
+	content,
+	topic_key,
+	tags,
+	content=memories,
+	content_rowid=rowid
+);
+
+CREATE TRIGGER IF NOT EXISTS memories_ai AFTER INSERT ON memories BEGIN
+	INSERT INTO fts_memories(rowid, content, topic_key, tags)
+	VALUES (new.rowid, new.content, new.topic_key, new.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS memories_ad AFTER DELETE ON memories BEGIN
+	INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
+	VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
+END;
+
+CREATE TRIGGER IF NOT EXISTS memories_au AFTER UPDATE ON memories BEGIN
+	INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
+	VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
+	INSERT INTO fts_memories(rowid, content, topic_key, tags)
+	VALUES (new.rowid, new.content, new.topic_key, new.tags);
+END;
+`
+
+// seedBaseline fills a baseline database the way its SaveMemory did:
+// "embedded" has a vector, "plain" has none, and "gone" is a vector left
+// behind by a deleted memory
+func seedBaseline(t *testing.T, db *DB) {
+	t.Helper()
+	if _, err := db.conn.Exec(baselineSchema); err != nil {
+		t.Fatalf("create baseline schema: %v", err)
+	}
+
+	vector := float32ToBytes(make([]float32, legacyEmbeddingDimensions))
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO memories (id, content, type, tags, trust, metadata, created_at, updated_at)
+			VALUES ('embedded', 'we use sqlite for storage', 'decision', '["db"]', 'validated', '{"project":"cortex"}', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')`, nil},
+		{`INSERT INTO memories (id, content, tags, metadata, created_at, updated_at)
+			VALUES ('plain', 'tabs over spaces', 'null', '{}', '2024-01-02T00:00:00Z', '2024-01-02T00:00:00Z')`, nil},
+		{`INSERT INTO embeddings (memory_id, embedding, model, created_at) VALUES ('embedded', ?, 'text-embedding-3-small', '2024-01-01T00:00:00Z')`, []interface{}{vector}},
+		{`INSERT INTO vec_memories (memory_id, embedding) VALUES ('embedded', ?)`, []interface{}{vector}},
+		{`INSERT INTO vec_memories (memory_id, embedding) VALUES ('gone', ?)`, []interface{}{vector}},
+	}
+	for _, s := range statements {
+		if _, err := db.conn.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("seed baseline: %v", err)
+		}
+	}
+}
+
+// openTestDB opens an empty database in a temporary directory. The test is
+// skipped if SQLite was built without FTS5 (go test -tags fts5).
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "cortex.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.conn.Exec("CREATE VIRTUAL TABLE temp.fts_probe USING fts5(content)"); err != nil {
+		t.Skipf("SQLite lacks FTS5, run the tests with -tags fts5: %v", err)
+	}
+	if _, err := db.conn.Exec("DROP TABLE temp.fts_probe"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, db *DB)
+		applied int
+		wantErr string
+		check   func(t *testing.T, db *DB)
+	}{
+		{
+			name:    "new database",
+			applied: LatestSchemaVersion(),
+		},
+		{
+			name:    "baseline schema is upgraded in place",
+			setup:   seedBaseline,
+			applied: LatestSchemaVersion(),
+			check:   checkUpgradedBaseline,
+		},
+		{
+			name: "current schema has nothing to apply",
+			setup: func(t *testing.T, db *DB) {
+				if _, err := db.Migrate(); err != nil {
+					t.Fatalf("first Migrate: %v", err)
+				}
+			},
+			applied: 0,
+		},
+		{
+			name: "newer schema is refused",
+			setup: func(t *testing.T, db *DB) {
+				if _, err := db.Migrate(); err != nil {
+					t.Fatalf("first Migrate: %v", err)
+				}
+				if _, err := db.conn.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', '2030-01-01T00:00:00Z')", LatestSchemaVersion()+1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "newer than this version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			if tt.setup != nil {
+				tt.setup(t, db)
+			}
+
+			applied, err := db.Migrate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			if len(applied) != tt.applied {
+				t.Errorf("applied %d migrations, want %d", len(applied), tt.applied)
+			}
+
+			version, err := db.SchemaVersion()
+			if err != nil {
+				t.Fatalf("SchemaVersion: %v", err)
+			}
+			if version != LatestSchemaVersion() {
+				t.Errorf("schema version = %d, want %d", version, LatestSchemaVersion())
+			}
+			pending, err := db.PendingMigrations()
+			if err != nil || len(pending) != 0 {
+				t.Errorf("pending migrations after Migrate: %v, %v", pending, err)
+			}
+
+			if tt.check != nil {
+				tt.check(t, db)
+			}
+		})
+	}
+}
+
+func checkUpgradedBaseline(t *testing.T, db *DB) {
+	for _, table := range []string{"schema_version", "meta", "memory_revisions", "sync_state", "embedding_cache", "pending_embeddings"} {
+		if ok, err := db.tableExists(table); err != nil || !ok {
+			t.Errorf("table %s missing after upgrade (%v)", table, err)
+		}
+	}
+
+	memory, err := db.GetMemory("embedded")
+	if err != nil || memory == nil {
+		t.Fatalf("GetMemory: %v, %v", memory, err)
+	}
+	if memory.Content != "we use sqlite for storage" || memory.Metadata.Project != "cortex" {
+		t.Errorf("memory changed by the upgrade: %+v", memory)
+	}
+
+	// The vector table gained filter columns and lost the orphaned vector
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM vec_memories").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("vec_memories has %d rows, want 1", count)
+	}
+	var project, typ, trust string
+	err = db.conn.QueryRow("SELECT project, type, trust FROM vec_memories WHERE memory_id = 'embedded'").Scan(&project, &typ, &trust)
+	if err != nil {
+		t.Fatalf("read vector metadata: %v", err)
+	}
+	if project != "cortex" || typ != "decision" || trust != "validated" {
+		t.Errorf("vector metadata = %s/%s/%s, want cortex/decision/validated", project, typ, trust)
+	}
+
+	// Only the memory without an embedding is queued
+	if p, err := db.GetPendingEmbedding("plain"); err != nil || p == nil {
+		t.Errorf("memory without embedding not queued: %v", err)
+	}
+	if p, err := db.GetPendingEmbedding("embedded"); err != nil || p != nil {
+		t.Errorf("embedded memory queued: %v, %v", p, err)
+	}
+
+	// Full-text search still sees the old rows
+	results, err := db.FTSSearch(FreeTextQuery("sqlite"), 5, types.RecallOptions{})
+	if err != nil {
+		t.Fatalf("FTSSearch: %v", err)
+	}
+	if len(results) != 1 || results[0].MemoryID != "embedded" {
+		t.Errorf("FTSSearch = %v, want only embedded", results)
+	}
+
+	// The pre-meta vector index is adopted as OpenAI's
+	if err := db.InitVectorIndex(legacyEmbeddingModel, legacyEmbeddingDimensions); err != nil {
+		t.Errorf("InitVectorIndex with the legacy model: %v", err)
+	}
+	var mismatch *EmbeddingMismatchError
+	if err := db.InitVectorIndex("nomic-embed-text", 768); !errors.As(err, &mismatch) {
+		t.Errorf("InitVectorIndex with another model = %v, want a mismatch", err)
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PendingEmbedding is a memory whose embedding failed and awaits a retry
+type PendingEmbedding struct {
+	MemoryID    string
+	Attempts    int       // Failed attempts so far
+	LastError   string    // Why the last attempt failed
+	NextAttempt time.Time // No retry before this time
+}
+
+// QueueEmbedding adds or updates a memory in the retry queue
+func (db *DB) QueueEmbedding(p *PendingEmbedding) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO pending_embeddings (memory_id, attempts, last_error, next_attempt, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(memory_id) DO UPDATE SET
+			attempts = excluded.attempts,
+			last_error = excluded.last_error,
+			next_attempt = excluded.next_attempt
+	`, p.MemoryID, p.Attempts, p.LastError, p.NextAttempt.UTC().Format(time.RFC3339),
+		time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// GetPendingEmbedding returns a memory's entry in the retry queue, or nil
+func (db *DB) GetPendingEmbedding(memoryID string) (*PendingEmbedding, error) {
+	rows, err := db.conn.Query(pendingQuery+" WHERE memory_id = ?", memoryID)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := scanPending(rows)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+	return pending[0], nil
+}
+
+// ListPendingEmbeddings returns up to limit queued memories due by the given
+// time, earliest first. A zero time returns them whether due or not.
+func (db *DB) ListPendingEmbeddings(due time.Time, limit int) ([]*PendingEmbedding, error) {
+	query := pendingQuery
+	var args []interface{}
+	if !due.IsZero() {
+		query += " WHERE next_attempt <= ?"
+		args = append(args, due.UTC().Format(time.RFC3339))
+	}
+	query += " ORDER BY next_attempt"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPending(rows)
+}
+
+// CountPendingEmbeddings returns how many memories are queued
+func (db *DB) CountPendingEmbeddings() (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM pending_embeddings").Scan(&count)
+	return count, err
+}
+
+// DeletePendingEmbedding removes a memory from the retry queue
+func (db *DB) DeletePendingEmbedding(memoryID string) error {
+	_, err := db.conn.Exec("DELETE FROM pending_embeddings WHERE memory_id = ?", memoryID)
+	return err
+}
+
+const pendingQuery = "SELECT memory_id, attempts, COALESCE(last_error, ''), next_attempt FROM pending_embeddings"
+
+func scanPending(rows *sql.Rows) ([]*PendingEmbedding, error) {
+	defer rows.Close()
+
+	var pending []*PendingEmbedding
+	for rows.Next() {
+		p := &PendingEmbedding{}
+		var next string
+		if err := rows.Scan(&p.MemoryID, &p.Attempts, &p.LastError, &next); err != nil {
+			return nil, err
+		}
+		p.NextAttempt, _ = time.Parse(time.RFC3339, next)
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// SaveRevision appends a revision for a memory, assigning the next revision
+// number, and returns that number
+func (db *DB) SaveRevision(r *types.Revision) (int, error) {
+	tagsJSON, _ := json.Marshal(r.Tags)
+
+	var rev int
+	err := db.conn.QueryRow(`
+		INSERT INTO memory_revisions (memory_id, rev, content, type, tags, trust, author, created_at)
+		SELECT ?, COALESCE(MAX(rev), 0) + 1, ?, ?, ?, ?, ?, ?
+		FROM memory_revisions WHERE memory_id = ?
+		RETURNING rev
+	`, r.MemoryID, r.Content, r.Type, string(tagsJSON), r.Trust, r.Author,
+		r.CreatedAt.Format(time.RFC3339), r.MemoryID).Scan(&rev)
+	if err != nil {
+		return 0, err
+	}
+
+	r.Rev = rev
+	return rev, nil
+}
+
+// GetRevisions returns all revisions of a memory, oldest first
+func (db *DB) GetRevisions(memoryID string) ([]*types.Revision, error) {
+	rows, err := db.conn.Query(`
+		SELECT memory_id, rev, content, type, tags, trust, author, created_at
+		FROM memory_revisions WHERE memory_id = ? ORDER BY rev
+	`, memoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*types.Revision
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	return revisions, rows.Err()
+}
+
+// CountRevisions returns the number of revisions recorded for a memory
+func (db *DB) CountRevisions(memoryID string) (int, error) {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM memory_revisions WHERE memory_id = ?", memoryID).Scan(&count)
+	return count, err
+}
+
+// GetRevision returns a single revision of a memory, or nil if it does not exist
+func (db *DB) GetRevision(memoryID string, rev int) (*types.Revision, error) {
+	row := db.conn.QueryRow(`
+		SELECT memory_id, rev, content, type, tags, trust, author, created_at
+		FROM memory_revisions WHERE memory_id = ? AND rev = ?
+	`, memoryID, rev)
+
+	r, err := scanRevision(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// scanRevision scans a row into a Revision struct
+func scanRevision(row interface{ Scan(...interface{}) error }) (*types.Revision, error) {
+	var r types.Revision
+	var tagsJSON, createdStr string
+	var author sql.NullString
+
+	if err := row.Scan(&r.MemoryID, &r.Rev, &r.Content, &r.Type, &tagsJSON, &r.Trust, &author, &createdStr); err != nil {
+		return nil, err
+	}
+
+	if author.Valid {
+		r.Author = author.String
+	}
+	json.Unmarshal([]byte(tagsJSON), &r.Tags)
+	r.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
+
+	return &r, nil
+}
@@ -5,22 +5,44 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
+	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 	"github.com/constantino-dev/cortex/pkg/types"
 	_ "github.com/mattn/go-sqlite3"
-	sqlite_vec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 )
 
 // DB wraps the SQLite database connection
 type DB struct {
-	conn *sql.DB
+	conn     *sql.DB
+	vecReady atomic.Bool // vec_memories is known to exist
+
+	countMu sync.Mutex
+	counts  map[string]int // Counter increments not yet written to meta
 }
 
-// New creates a new database connection and initializes schema
+// New creates a new database connection and applies pending schema migrations
 func New(path string) (*DB, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate: %w", err)
+	}
+
+	return db, nil
+}
+
+// Open creates a new database connection without touching the schema
+func Open(path string) (*DB, error) {
 	// Register sqlite-vec extension
 	sqlite_vec.Auto()
 
@@ -29,108 +51,402 @@ func New(path string) (*DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	return &DB{conn: conn}, nil
+}
+
+// OpenReadOnly opens an existing database that must never be written to.
+// Since it can't be migrated, its schema has to be the one this build expects.
+func OpenReadOnly(path string) (*DB, error) {
+	sqlite_vec.Auto()
+
+	conn, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
 	db := &DB{conn: conn}
-	if err := db.migrate(); err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to migrate: %w", err)
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version != LatestSchemaVersion() {
+		db.Close()
+		return nil, &SchemaMismatchError{Version: version, Expected: LatestSchemaVersion()}
 	}
 
 	return db, nil
 }
 
-// Close closes the database connection
+// SchemaMismatchError is returned when a read-only database has a schema
+// version other than the one this build expects
+type SchemaMismatchError struct {
+	Version  int
+	Expected int
+}
+
+func (e *SchemaMismatchError) Error() string {
+	if e.Version > e.Expected {
+		return fmt.Sprintf("database schema version %d is newer than this version of cortex supports (%d)", e.Version, e.Expected)
+	}
+	return fmt.Sprintf("database schema version %d is older than this version of cortex expects (%d); open it writable once to migrate it", e.Version, e.Expected)
+}
+
+// Close writes pending counters and closes the database connection
 func (db *DB) Close() error {
-	return db.conn.Close()
-}
-
-// migrate creates the database schema
-func (db *DB) migrate() error {
-	schema := `
-	-- Memories table
-	CREATE TABLE IF NOT EXISTS memories (
-		id TEXT PRIMARY KEY,
-		content TEXT NOT NULL,
-		type TEXT NOT NULL DEFAULT 'general',
-		topic_key TEXT,
-		tags TEXT, -- JSON array
-		trust TEXT NOT NULL DEFAULT 'proposed',
-		metadata TEXT, -- JSON object
-		created_at TEXT NOT NULL,
-		updated_at TEXT NOT NULL,
-		access_count INTEGER DEFAULT 0
-	);
-
-	-- Index for topic_key lookups and evolution
-	CREATE INDEX IF NOT EXISTS idx_memories_topic_key ON memories(topic_key);
-	CREATE INDEX IF NOT EXISTS idx_memories_type ON memories(type);
-	CREATE INDEX IF NOT EXISTS idx_memories_trust ON memories(trust);
-	CREATE INDEX IF NOT EXISTS idx_memories_created ON memories(created_at);
-
-	-- Relations table
-	CREATE TABLE IF NOT EXISTS relations (
-		id TEXT PRIMARY KEY,
-		from_id TEXT NOT NULL,
-		to_id TEXT NOT NULL,
-		type TEXT NOT NULL,
-		note TEXT,
-		created_at TEXT NOT NULL,
-		FOREIGN KEY (from_id) REFERENCES memories(id) ON DELETE CASCADE,
-		FOREIGN KEY (to_id) REFERENCES memories(id) ON DELETE CASCADE
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_id);
-	CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_id);
-	CREATE INDEX IF NOT EXISTS idx_relations_type ON relations(type);
-
-	-- Embeddings cache
-	CREATE TABLE IF NOT EXISTS embeddings (
-		memory_id TEXT PRIMARY KEY,
-		embedding BLOB NOT NULL, -- float32 array as blob
-		model TEXT NOT NULL,
-		created_at TEXT NOT NULL,
-		FOREIGN KEY (memory_id) REFERENCES memories(id) ON DELETE CASCADE
-	);
-
-	-- Virtual table for vector search (sqlite-vec)
-	CREATE VIRTUAL TABLE IF NOT EXISTS vec_memories USING vec0(
-		memory_id TEXT PRIMARY KEY,
-		embedding float[1536]
-	);
-
-	-- Full-text search for keyword matching
-	CREATE VIRTUAL TABLE IF NOT EXISTS fts_memories USING fts5(
-		content,
-		topic_key,
-		tags,
-		content=memories,
-		content_rowid=rowid
-	);
-
-	-- Triggers to keep FTS in sync
-	CREATE TRIGGER IF NOT EXISTS memories_ai AFTER INSERT ON memories BEGIN
-		INSERT INTO fts_memories(rowid, content, topic_key, tags)
-		VALUES (new.rowid, new.content, new.topic_key, new.tags);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS memories_ad AFTER DELETE ON memories BEGIN
-		INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
-		VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS memories_au AFTER UPDATE ON memories BEGIN
-		INSERT INTO fts_memories(fts_memories, rowid, content, topic_key, tags)
-		VALUES('delete', old.rowid, old.content, old.topic_key, old.tags);
-		INSERT INTO fts_memories(rowid, content, topic_key, tags)
-		VALUES (new.rowid, new.content, new.topic_key, new.tags);
-	END;
-	`
+	flushErr := db.FlushCounts()
+	if err := db.conn.Close(); err != nil {
+		return err
+	}
+	if flushErr != nil {
+		return fmt.Errorf("failed to save counters: %w", flushErr)
+	}
+	return nil
+}
+
+// Metadata keys describing the vector index
+const (
+	metaEmbeddingModel      = "embedding_model"
+	metaEmbeddingDimensions = "embedding_dimensions"
+	metaReembedTarget       = "reembed_target" // set while a re-embed is running
+)
+
+// Databases created before the meta table existed used a fixed schema
+const (
+	legacyEmbeddingModel      = "text-embedding-3-small"
+	legacyEmbeddingDimensions = 1536
+)
+
+// EmbeddingMismatchError is returned when the configured embedding provider
+// differs from the one the vector index was built with
+type EmbeddingMismatchError struct {
+	StoredModel      string
+	StoredDimensions int
+	Model            string
+	Dimensions       int
+}
+
+func (e *EmbeddingMismatchError) Error() string {
+	configured := fmt.Sprintf("%q (%d dimensions)", e.Model, e.Dimensions)
+	if e.Dimensions == 0 {
+		configured = fmt.Sprintf("%q", e.Model)
+	}
+	return fmt.Sprintf("database was built with embedding model %q (%d dimensions) but the configured provider uses %s",
+		e.StoredModel, e.StoredDimensions, configured)
+}
+
+// ReembedInterruptedError is returned when a previous re-embed did not finish,
+// leaving part of the store without vectors
+type ReembedInterruptedError struct {
+	Model string
+}
+
+func (e *ReembedInterruptedError) Error() string {
+	return fmt.Sprintf("re-embedding with model %q was interrupted", e.Model)
+}
+
+// GetMeta returns a metadata value, or "" if the key is not set
+func (db *DB) GetMeta(key string) (string, error) {
+	var value string
+	err := db.conn.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetMeta stores a metadata value
+func (db *DB) SetMeta(key, value string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
+
+// addMetaSQL adds to a numeric metadata value, starting from 0
+const addMetaSQL = `
+	INSERT INTO meta (key, value) VALUES (?, ?)
+	ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + excluded.value
+`
+
+// DeleteMeta removes a metadata value
+func (db *DB) DeleteMeta(key string) error {
+	_, err := db.conn.Exec("DELETE FROM meta WHERE key = ?", key)
+	return err
+}
+
+// EmbeddingModel returns the model and dimension the vector index was built with.
+// An empty model means the index has not been created yet.
+func (db *DB) EmbeddingModel() (string, int, error) {
+	model, err := db.GetMeta(metaEmbeddingModel)
+	if err != nil {
+		return "", 0, err
+	}
+	dimStr, err := db.GetMeta(metaEmbeddingDimensions)
+	if err != nil {
+		return "", 0, err
+	}
+	if model == "" || dimStr == "" {
+		return "", 0, nil
+	}
+
+	dims, err := strconv.Atoi(dimStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid %s in meta table: %q", metaEmbeddingDimensions, dimStr)
+	}
+	return model, dims, nil
+}
+
+// setEmbeddingModel records the model and dimension of the vector index
+func (db *DB) setEmbeddingModel(model string, dimensions int) error {
+	if err := db.SetMeta(metaEmbeddingModel, model); err != nil {
+		return err
+	}
+	return db.SetMeta(metaEmbeddingDimensions, strconv.Itoa(dimensions))
+}
+
+// InitVectorIndex creates the vector table for the given embedding model, or
+// verifies that an existing one was built with the same model and dimension.
+// A dimension of 0 means it isn't known yet: an existing table is checked
+// against the model alone, and a missing one is left to be created later.
+func (db *DB) InitVectorIndex(model string, dimensions int) error {
+	if dimensions < 0 {
+		return fmt.Errorf("invalid embedding dimensions: %d", dimensions)
+	}
+
+	target, err := db.GetMeta(metaReembedTarget)
+	if err != nil {
+		return err
+	}
+	if target != "" {
+		return &ReembedInterruptedError{Model: target}
+	}
+
+	storedModel, storedDims, err := db.EmbeddingModel()
+	if err != nil {
+		return err
+	}
+
+	if storedModel == "" {
+		// Databases from before the meta table have a 1536D vector table
+		// filled by OpenAI; adopt that instead of rebuilding it.
+		exists, err := db.tableExists("vec_memories")
+		if err != nil {
+			return err
+		}
+		if exists {
+			storedModel, storedDims = legacyEmbeddingModel, legacyEmbeddingDimensions
+		} else {
+			if dimensions == 0 {
+				return nil
+			}
+			if err := db.createVecTable(dimensions); err != nil {
+				return err
+			}
+			storedModel, storedDims = model, dimensions
+		}
+		if err := db.setEmbeddingModel(storedModel, storedDims); err != nil {
+			return err
+		}
+	}
+
+	return checkEmbeddingModel(storedModel, storedDims, model, dimensions)
+}
+
+// CheckVectorIndex is InitVectorIndex for a read-only database: it reports
+// an interrupted re-embed or a mismatched index, but never creates or records
+// one. A database without vectors passes.
+func (db *DB) CheckVectorIndex(model string, dimensions int) error {
+	target, err := db.GetMeta(metaReembedTarget)
+	if err != nil {
+		return err
+	}
+	if target != "" {
+		return &ReembedInterruptedError{Model: target}
+	}
+
+	storedModel, storedDims, err := db.EmbeddingModel()
+	if err != nil {
+		return err
+	}
+	if storedModel == "" {
+		exists, err := db.tableExists("vec_memories")
+		if err != nil || !exists {
+			return err
+		}
+		storedModel, storedDims = legacyEmbeddingModel, legacyEmbeddingDimensions
+	}
+
+	return checkEmbeddingModel(storedModel, storedDims, model, dimensions)
+}
+
+// checkEmbeddingModel compares the index's model with the provider's. A
+// dimension of 0 is not known yet and matches any.
+func checkEmbeddingModel(storedModel string, storedDims int, model string, dimensions int) error {
+	if storedModel != model || (dimensions != 0 && storedDims != dimensions) {
+		return &EmbeddingMismatchError{
+			StoredModel:      storedModel,
+			StoredDimensions: storedDims,
+			Model:            model,
+			Dimensions:       dimensions,
+		}
+	}
+	return nil
+}
+
+// RebuildVectorIndex recreates vec_memories for a new embedding model and
+// refills it from the stored embeddings that already use that model. The store
+// is marked as mid-re-embed until FinishReembed is called, so an interrupted
+// run can be resumed by calling this again.
+func (db *DB) RebuildVectorIndex(model string, dimensions int) (int, error) {
+	if dimensions <= 0 {
+		return 0, fmt.Errorf("invalid embedding dimensions: %d", dimensions)
+	}
+
+	if err := db.SetMeta(metaReembedTarget, model); err != nil {
+		return 0, err
+	}
+	if err := db.setEmbeddingModel(model, dimensions); err != nil {
+		return 0, err
+	}
+
+	if _, err := db.conn.Exec("DROP TABLE IF EXISTS vec_memories"); err != nil {
+		return 0, fmt.Errorf("failed to drop vector table: %w", err)
+	}
+	if err := db.createVecTable(dimensions); err != nil {
+		return 0, fmt.Errorf("failed to create vector table: %w", err)
+	}
+
+	rows, err := db.conn.Query("SELECT memory_id, embedding FROM embeddings WHERE model = ?", model)
+	if err != nil {
+		return 0, err
+	}
+
+	type stored struct {
+		id  string
+		emb []float32
+	}
+	var current []stored
+	for rows.Next() {
+		var id string
+		var embBytes []byte
+		if err := rows.Scan(&id, &embBytes); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if len(embBytes) != dimensions*4 {
+			continue
+		}
+		current = append(current, stored{id: id, emb: bytesToFloat32(embBytes)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, s := range current {
+		if _, err := db.conn.Exec(insertVecSQL, serializeVector(s.emb), s.id); err != nil {
+			return 0, fmt.Errorf("failed to restore vector for %s: %w", s.id, err)
+		}
+	}
+
+	return len(current), nil
+}
+
+// FinishReembed clears the mid-re-embed marker set by RebuildVectorIndex
+func (db *DB) FinishReembed() error {
+	return db.DeleteMeta(metaReembedTarget)
+}
+
+// createVecTable creates the sqlite-vec table for vectors of the given dimension
+func (db *DB) createVecTable(dimensions int) error {
+	_, err := db.conn.Exec(vecTableSQL(dimensions))
+	return err
+}
+
+// hasVecTable reports whether vec_memories exists. A store whose provider
+// hasn't reported its dimension yet gets one only on its first embedding.
+func (db *DB) hasVecTable() (bool, error) {
+	if db.vecReady.Load() {
+		return true, nil
+	}
+	exists, err := db.tableExists("vec_memories")
+	if exists {
+		db.vecReady.Store(true)
+	}
+	return exists, err
+}
+
+// vecTableSQL returns the statement creating vec_memories. Besides the vector
+// it carries the memory's project (as partition key), type and trust, so
+// VectorSearch can filter on them inside the KNN query. SaveMemory and
+// UpdateTrust keep them current.
+func vecTableSQL(dimensions int) string {
+	return fmt.Sprintf(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS vec_memories USING vec0(
+			memory_id TEXT PRIMARY KEY,
+			embedding float[%d],
+			project TEXT PARTITION KEY,
+			type TEXT,
+			trust TEXT
+		)
+	`, dimensions)
+}
+
+// vecProjectExpr reads a memory's project for vec_memories, which can't hold NULL
+const vecProjectExpr = "COALESCE(json_extract(m.metadata, '$.project'), '')"
+
+// insertVecSQL inserts a vector with the metadata of its memory; nothing is
+// inserted if the memory does not exist. Arguments: vector, memory ID.
+const insertVecSQL = `
+	INSERT INTO vec_memories (memory_id, embedding, project, type, trust)
+	SELECT m.id, ?, ` + vecProjectExpr + `, m.type, m.trust
+	FROM memories m WHERE m.id = ?
+`
+
+// syncVecMetadata copies a memory's project, type and trust to its vector.
+// The project is a partition key, which sqlite-vec can't update in place, so
+// a changed project re-inserts the vector.
+func (db *DB) syncVecMetadata(q execer, m *types.Memory) error {
+	if ok, err := db.hasVecTable(); !ok {
+		return err
+	}
+
+	var project string
+	var embedding []byte
+	err := q.QueryRow("SELECT project, embedding FROM vec_memories WHERE memory_id = ?", m.ID).Scan(&project, &embedding)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-	_, err := db.conn.Exec(schema)
+	if project == m.Metadata.Project {
+		_, err := q.Exec("UPDATE vec_memories SET type = ?, trust = ? WHERE memory_id = ?", m.Type, m.Trust, m.ID)
+		return err
+	}
+
+	if _, err := q.Exec("DELETE FROM vec_memories WHERE memory_id = ?", m.ID); err != nil {
+		return err
+	}
+	_, err = q.Exec(insertVecSQL, embedding, m.ID)
 	return err
 }
 
+// execer runs statements on the connection or inside a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // SaveMemory stores or updates a memory
 func (db *DB) SaveMemory(m *types.Memory) error {
+	return db.saveMemory(db.conn, m)
+}
+
+func (db *DB) saveMemory(q execer, m *types.Memory) error {
 	tagsJSON, _ := json.Marshal(m.Tags)
 	metaJSON, _ := json.Marshal(m.Metadata)
 
@@ -148,12 +464,16 @@ func (db *DB) SaveMemory(m *types.Memory) error {
 			access_count = excluded.access_count
 	`
 
-	_, err := db.conn.Exec(query,
+	_, err := q.Exec(query,
 		m.ID, m.Content, m.Type, m.TopicKey, string(tagsJSON),
 		m.Trust, string(metaJSON), m.CreatedAt.Format(time.RFC3339),
 		m.UpdatedAt.Format(time.RFC3339), m.AccessCnt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return db.syncVecMetadata(q, m)
 }
 
 // GetMemory retrieves a memory by ID
@@ -199,8 +519,17 @@ func (db *DB) scanMemory(row *sql.Row) (*types.Memory, error) {
 	return &m, nil
 }
 
-// ListMemories returns memories matching the given filters
-func (db *DB) ListMemories(opts types.RecallOptions) ([]*types.Memory, error) {
+// filterConditions translates the filters of RecallOptions into SQL
+// conditions on the memories table, qualified with alias if given. Limit and
+// MinScore are not filters and are ignored.
+func filterConditions(opts types.RecallOptions, alias string) ([]string, []interface{}) {
+	col := func(name string) string {
+		if alias == "" {
+			return name
+		}
+		return alias + "." + name
+	}
+
 	var conditions []string
 	var args []interface{}
 
@@ -210,7 +539,7 @@ func (db *DB) ListMemories(opts types.RecallOptions) ([]*types.Memory, error) {
 			placeholders[i] = "?"
 			args = append(args, t)
 		}
-		conditions = append(conditions, fmt.Sprintf("type IN (%s)", strings.Join(placeholders, ",")))
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", col("type"), strings.Join(placeholders, ",")))
 	}
 
 	if len(opts.TrustLevels) > 0 {
@@ -219,19 +548,37 @@ func (db *DB) ListMemories(opts types.RecallOptions) ([]*types.Memory, error) {
 			placeholders[i] = "?"
 			args = append(args, t)
 		}
-		conditions = append(conditions, fmt.Sprintf("trust IN (%s)", strings.Join(placeholders, ",")))
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", col("trust"), strings.Join(placeholders, ",")))
+	}
+
+	// A memory matches if it has any of the tags
+	if len(opts.Tags) > 0 {
+		placeholders := make([]string, len(opts.Tags))
+		for i, t := range opts.Tags {
+			placeholders[i] = "?"
+			args = append(args, t)
+		}
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value IN (%s))",
+			col("tags"), strings.Join(placeholders, ",")))
 	}
 
 	if opts.Project != "" {
-		conditions = append(conditions, "json_extract(metadata, '$.project') = ?")
+		conditions = append(conditions, fmt.Sprintf("json_extract(%s, '$.project') = ?", col("metadata")))
 		args = append(args, opts.Project)
 	}
 
 	if opts.TopicKey != "" {
-		conditions = append(conditions, "topic_key LIKE ?")
+		conditions = append(conditions, fmt.Sprintf("%s LIKE ?", col("topic_key")))
 		args = append(args, opts.TopicKey+"%")
 	}
 
+	return conditions, args
+}
+
+// ListMemories returns memories matching the given filters
+func (db *DB) ListMemories(opts types.RecallOptions) ([]*types.Memory, error) {
+	conditions, args := filterConditions(opts, "")
+
 	query := "SELECT id, content, type, topic_key, tags, trust, metadata, created_at, updated_at, access_count FROM memories"
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -273,10 +620,38 @@ func (db *DB) ListMemories(opts types.RecallOptions) ([]*types.Memory, error) {
 	return memories, nil
 }
 
-// DeleteMemory removes a memory by ID
+// DeleteMemory removes a memory by ID along with its relations, revisions
+// and embedding
 func (db *DB) DeleteMemory(id string) error {
-	_, err := db.conn.Exec("DELETE FROM memories WHERE id = ?", id)
-	return err
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	hasVec, err := db.hasVecTable()
+	if err != nil {
+		return err
+	}
+
+	// Foreign keys are not enforced on this connection, so cascade by hand
+	statements := []string{
+		"DELETE FROM relations WHERE ? IN (from_id, to_id)",
+		"DELETE FROM memory_revisions WHERE memory_id = ?",
+		"DELETE FROM embeddings WHERE memory_id = ?",
+		"DELETE FROM pending_embeddings WHERE memory_id = ?",
+		"DELETE FROM memories WHERE id = ?",
+	}
+	if hasVec {
+		statements = append(statements, "DELETE FROM vec_memories WHERE memory_id = ?")
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // IncrementAccessCount increments the access count for a memory
@@ -287,18 +662,86 @@ func (db *DB) IncrementAccessCount(id string) error {
 
 // UpdateTrust updates the trust level of a memory
 func (db *DB) UpdateTrust(id string, trust types.TrustLevel) error {
-	_, err := db.conn.Exec("UPDATE memories SET trust = ?, updated_at = ? WHERE id = ?",
+	return db.updateTrust(db.conn, id, trust)
+}
+
+func (db *DB) updateTrust(q execer, id string, trust types.TrustLevel) error {
+	_, err := q.Exec("UPDATE memories SET trust = ?, updated_at = ? WHERE id = ?",
 		trust, time.Now().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+	if ok, err := db.hasVecTable(); !ok {
+		return err
+	}
+
+	_, err = q.Exec("UPDATE vec_memories SET trust = ? WHERE memory_id = ?", trust, id)
 	return err
 }
 
 // SaveRelation stores a relation between two memories
 func (db *DB) SaveRelation(r *types.Relation) error {
+	return saveRelation(db.conn, r)
+}
+
+func saveRelation(q execer, r *types.Relation) error {
 	query := `INSERT INTO relations (id, from_id, to_id, type, note, created_at) VALUES (?, ?, ?, ?, ?, ?)`
-	_, err := db.conn.Exec(query, r.ID, r.FromID, r.ToID, r.Type, r.Note, r.CreatedAt.Format(time.RFC3339))
+	_, err := q.Exec(query, r.ID, r.FromID, r.ToID, r.Type, r.Note, r.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// repointRelations moves every relation of oldID to newID, dropping the
+// self-relations and duplicate relations this creates
+func repointRelations(q execer, oldID, newID string) error {
+	statements := []string{
+		"UPDATE relations SET from_id = ? WHERE from_id = ?",
+		"UPDATE relations SET to_id = ? WHERE to_id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := q.Exec(stmt, newID, oldID); err != nil {
+			return err
+		}
+	}
+
+	_, err := q.Exec("DELETE FROM relations WHERE from_id = to_id AND from_id = ?", newID)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`
+		DELETE FROM relations WHERE ? IN (from_id, to_id) AND rowid NOT IN (
+			SELECT MIN(rowid) FROM relations GROUP BY from_id, to_id, type
+		)`, newID)
 	return err
 }
 
+// MergeMemories saves a merge survivor and retires its duplicates in one
+// transaction. Each relation in replaces links the survivor to a duplicate,
+// whose own relations move to the survivor before it is marked obsolete.
+func (db *DB) MergeMemories(survivor *types.Memory, replaces []*types.Relation) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.saveMemory(tx, survivor); err != nil {
+		return fmt.Errorf("failed to save memory: %w", err)
+	}
+	for _, r := range replaces {
+		if err := repointRelations(tx, r.ToID, survivor.ID); err != nil {
+			return fmt.Errorf("failed to move relations of %s: %w", r.ToID, err)
+		}
+		if err := saveRelation(tx, r); err != nil {
+			return fmt.Errorf("failed to save relation: %w", err)
+		}
+		if err := db.updateTrust(tx, r.ToID, types.TrustObsolete); err != nil {
+			return fmt.Errorf("failed to retire %s: %w", r.ToID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 // GetRelationsFrom returns all relations starting from a memory
 func (db *DB) GetRelationsFrom(memoryID string) ([]*types.Relation, error) {
 	return db.getRelations("from_id = ?", memoryID)
@@ -309,9 +752,9 @@ func (db *DB) GetRelationsTo(memoryID string) ([]*types.Relation, error) {
 	return db.getRelations("to_id = ?", memoryID)
 }
 
-func (db *DB) getRelations(condition string, arg interface{}) ([]*types.Relation, error) {
+func (db *DB) getRelations(condition string, args ...interface{}) ([]*types.Relation, error) {
 	query := fmt.Sprintf("SELECT id, from_id, to_id, type, note, created_at FROM relations WHERE %s", condition)
-	rows, err := db.conn.Query(query, arg)
+	rows, err := db.conn.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -338,6 +781,20 @@ func (db *DB) getRelations(condition string, arg interface{}) ([]*types.Relation
 	return relations, nil
 }
 
+// GetRelation retrieves a relation by ID, or returns nil if there is none
+func (db *DB) GetRelation(id string) (*types.Relation, error) {
+	relations, err := db.getRelations("id = ?", id)
+	if err != nil || len(relations) == 0 {
+		return nil, err
+	}
+	return relations[0], nil
+}
+
+// ListRelations returns all relations
+func (db *DB) ListRelations() ([]*types.Relation, error) {
+	return db.getRelations("1")
+}
+
 // DeleteRelation removes a relation by ID
 func (db *DB) DeleteRelation(id string) error {
 	_, err := db.conn.Exec("DELETE FROM relations WHERE id = ?", id)
@@ -363,12 +820,13 @@ func (db *DB) SaveEmbedding(memoryID string, embedding []float32, model string)
 	// Save to vec_memories for vector search
 	// sqlite-vec virtual tables don't support ON CONFLICT, so delete first
 	db.conn.Exec(`DELETE FROM vec_memories WHERE memory_id = ?`, memoryID)
-	_, err = db.conn.Exec(`
-		INSERT INTO vec_memories (memory_id, embedding)
-		VALUES (?, ?)
-	`, memoryID, serializeVector(embedding))
+	_, err = db.conn.Exec(insertVecSQL, serializeVector(embedding), memoryID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// The memory no longer waits for a retry
+	return db.DeletePendingEmbedding(memoryID)
 }
 
 // GetEmbedding retrieves an embedding for a memory
@@ -384,33 +842,121 @@ func (db *DB) GetEmbedding(memoryID string) ([]float32, error) {
 	return bytesToFloat32(embBytes), nil
 }
 
-// VectorSearch performs semantic search using sqlite-vec
-func (db *DB) VectorSearch(queryEmb []float32, limit int) ([]struct {
-	MemoryID string
-	Distance float64
-}, error) {
-	// sqlite-vec requires k=? constraint for KNN queries
-	query := `
-		SELECT memory_id, distance
-		FROM vec_memories
-		WHERE embedding MATCH ? AND k = ?
-	`
+// GetEmbeddingWithModel retrieves an embedding for a memory and the model that produced it
+func (db *DB) GetEmbeddingWithModel(memoryID string) ([]float32, string, error) {
+	var embBytes []byte
+	var model string
+	err := db.conn.QueryRow("SELECT embedding, model FROM embeddings WHERE memory_id = ?", memoryID).Scan(&embBytes, &model)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	return bytesToFloat32(embBytes), model, nil
+}
 
-	rows, err := db.conn.Query(query, serializeVector(queryEmb), limit)
+// CountStaleEmbeddings returns how many memories have no embedding from the given model
+func (db *DB) CountStaleEmbeddings(model string) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM memories m
+		LEFT JOIN embeddings e ON e.memory_id = m.id
+		WHERE e.model IS NULL OR e.model != ?
+	`, model).Scan(&count)
+	return count, err
+}
+
+// ListStaleEmbeddings returns up to limit memories that have no embedding from the given model
+func (db *DB) ListStaleEmbeddings(model string, limit int) ([]*types.Memory, error) {
+	rows, err := db.conn.Query(`
+		SELECT m.id, m.content
+		FROM memories m
+		LEFT JOIN embeddings e ON e.memory_id = m.id
+		WHERE e.model IS NULL OR e.model != ?
+		ORDER BY m.rowid
+		LIMIT ?
+	`, model, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []struct {
-		MemoryID string
-		Distance float64
-	}
+	var memories []*types.Memory
 	for rows.Next() {
-		var r struct {
-			MemoryID string
-			Distance float64
+		var m types.Memory
+		if err := rows.Scan(&m.ID, &m.Content); err != nil {
+			return nil, err
+		}
+		memories = append(memories, &m)
+	}
+
+	return memories, rows.Err()
+}
+
+// VectorResult is one nearest-neighbor match
+type VectorResult struct {
+	MemoryID string
+	Distance float64 // L2 distance, lower is closer
+}
+
+// VectorSearch performs semantic search using sqlite-vec, returning the
+// nearest memories that match the filters of opts
+func (db *DB) VectorSearch(queryEmb []float32, limit int, opts types.RecallOptions) ([]VectorResult, error) {
+	if ok, err := db.hasVecTable(); !ok {
+		return nil, err
+	}
+
+	var query string
+	var args []interface{}
+
+	if len(opts.Tags) == 0 && opts.TopicKey == "" {
+		// Project, type and trust are columns of vec_memories, so the KNN
+		// query returns the top k among matching memories only.
+		// sqlite-vec requires k=? constraint for KNN queries
+		conditions := []string{"embedding MATCH ?", "k = ?"}
+		args = []interface{}{serializeVector(queryEmb), limit}
+
+		filter, filterArgs := filterConditions(types.RecallOptions{Types: opts.Types, TrustLevels: opts.TrustLevels}, "")
+		conditions = append(conditions, filter...)
+		args = append(args, filterArgs...)
+		if opts.Project != "" {
+			conditions = append(conditions, "project = ?")
+			args = append(args, opts.Project)
 		}
+
+		query = fmt.Sprintf(`
+			SELECT memory_id, distance
+			FROM vec_memories
+			WHERE %s
+			ORDER BY distance
+		`, strings.Join(conditions, " AND "))
+	} else {
+		// Tags and topic keys live only in the memories table, which a KNN
+		// query can't see; scan the matching memories instead
+		conditions, filterArgs := filterConditions(opts, "m")
+		query = fmt.Sprintf(`
+			SELECT v.memory_id, vec_distance_l2(v.embedding, ?) AS distance
+			FROM vec_memories v
+			JOIN memories m ON m.id = v.memory_id
+			WHERE %s
+			ORDER BY distance
+			LIMIT ?
+		`, strings.Join(conditions, " AND "))
+		args = append([]interface{}{serializeVector(queryEmb)}, filterArgs...)
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []VectorResult
+	for rows.Next() {
+		var r VectorResult
 		if err := rows.Scan(&r.MemoryID, &r.Distance); err != nil {
 			return nil, err
 		}
@@ -420,31 +966,51 @@ func (db *DB) VectorSearch(queryEmb []float32, limit int) ([]struct {
 	return results, nil
 }
 
-// FTSSearch performs full-text search
-func (db *DB) FTSSearch(query string, limit int) ([]string, error) {
-	rows, err := db.conn.Query(`
-		SELECT m.id
+// FTSResult is one full-text match
+type FTSResult struct {
+	MemoryID string
+	Score    float64 // BM25 relevance, higher is better
+}
+
+// FTSSearch performs full-text search over memories matching the filters of
+// opts, best match first. The query is an fts5 expression, as built by
+// FreeTextQuery or ParseFTSQuery.
+func (db *DB) FTSSearch(query string, limit int, opts types.RecallOptions) ([]FTSResult, error) {
+	conditions, args := filterConditions(opts, "m")
+	conditions = append([]string{"fts_memories MATCH ?"}, conditions...)
+	args = append([]interface{}{query}, args...)
+	args = append(args, limit)
+
+	// bm25() is negative, more negative for better matches
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT m.id, -bm25(fts_memories) AS score
 		FROM fts_memories f
 		JOIN memories m ON f.rowid = m.rowid
-		WHERE fts_memories MATCH ?
-		ORDER BY rank
+		WHERE %s
+		ORDER BY score DESC
 		LIMIT ?
-	`, query, limit)
+	`, strings.Join(conditions, " AND ")), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var ids []string
+	var results []FTSResult
 	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
+		var r FTSResult
+		if err := rows.Scan(&r.MemoryID, &r.Score); err != nil {
 			return nil, err
 		}
-		ids = append(ids, id)
+		results = append(results, r)
 	}
 
-	return ids, nil
+	return results, rows.Err()
+}
+
+// RebuildFTS regenerates the full-text index from the memories table
+func (db *DB) RebuildFTS() error {
+	_, err := db.conn.Exec("INSERT INTO fts_memories(fts_memories) VALUES('rebuild')")
+	return err
 }
 
 // Stats returns database statistics
@@ -461,6 +1027,28 @@ func (db *DB) Stats() (map[string]int, error) {
 	db.conn.QueryRow("SELECT COUNT(*) FROM embeddings").Scan(&count)
 	stats["embeddings"] = count
 
+	var unembedded int
+	db.conn.QueryRow(`
+		SELECT COUNT(*) FROM memories m
+		LEFT JOIN embeddings e ON e.memory_id = m.id
+		WHERE e.memory_id IS NULL
+	`).Scan(&unembedded)
+	stats["unembedded"] = unembedded
+
+	var pending int
+	db.conn.QueryRow("SELECT COUNT(*) FROM pending_embeddings").Scan(&pending)
+	stats["pending_embeddings"] = pending
+
+	var cached int
+	db.conn.QueryRow("SELECT COUNT(*) FROM embedding_cache").Scan(&cached)
+	stats["cache_entries"] = cached
+
+	for _, key := range CacheCounters {
+		value, _ := db.GetMeta(key)
+		n, _ := strconv.Atoi(value)
+		stats[key] = n + db.counted(key)
+	}
+
 	return stats, nil
 }
 
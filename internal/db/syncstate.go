@@ -0,0 +1,41 @@
+package db
+
+// GetSyncState returns the file hash of every memory as of the last sync
+// with the mirror in dir
+func (db *DB) GetSyncState(dir string) (map[string]string, error) {
+	rows, err := db.conn.Query("SELECT memory_id, hash FROM sync_state WHERE dir = ?", dir)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	state := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			return nil, err
+		}
+		state[id] = hash
+	}
+	return state, rows.Err()
+}
+
+// SetSyncState replaces the recorded state of the mirror in dir
+func (db *DB) SetSyncState(dir string, state map[string]string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM sync_state WHERE dir = ?", dir); err != nil {
+		return err
+	}
+	for id, hash := range state {
+		if _, err := tx.Exec("INSERT INTO sync_state (dir, memory_id, hash) VALUES (?, ?, ?)", dir, id, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,263 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultOllamaURL   = "http://localhost:11434"
+	DefaultOllamaModel = "nomic-embed-text"
+)
+
+// probeTimeout bounds the probe NewOllama makes to learn the model's dimension
+const probeTimeout = 3 * time.Second
+
+// errLegacyAPI signals that the server does not know /api/embed
+var errLegacyAPI = errors.New("ollama: /api/embed not supported")
+
+// Ollama implements the Provider interface using a local Ollama server
+type Ollama struct {
+	client     *http.Client
+	baseURL    string
+	model      string
+	dimensions atomic.Int64 // 0 until known
+
+	// legacy is set once the server answered 404 on /api/embed, after which
+	// every request goes through the older one-text-per-call /api/embeddings.
+	legacy atomic.Bool
+}
+
+// NewOllama creates a new Ollama embedding provider. dimensions is the
+// model's vector dimension if already known (e.g. from a store built with the
+// model). Otherwise a probe text is embedded to learn it; if the server can't
+// be reached, it is learned from the first embedding instead.
+func NewOllama(baseURL, model string, dimensions int) *Ollama {
+	return NewOllamaWithClient(baseURL, model, dimensions, &http.Client{Timeout: 2 * time.Minute})
+}
+
+// NewOllamaWithClient creates a new Ollama provider using the given HTTP client
+func NewOllamaWithClient(baseURL, model string, dimensions int, client *http.Client) *Ollama {
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+
+	o := &Ollama{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+	}
+	o.dimensions.Store(int64(dimensions))
+
+	if dimensions == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		o.Embed(ctx, "dimension probe")
+	}
+	return o
+}
+
+// Embed generates an embedding for a single text
+func (o *Ollama) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	return embeddings[0], nil
+}
+
+// EmbedBatch generates embeddings for multiple texts
+func (o *Ollama) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	embeddings, err := o.embedAll(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recall and dedupe compare vectors by L2 distance, which only matches
+	// cosine similarity for unit vectors. /api/embed normalizes, but
+	// /api/embeddings doesn't.
+	// A vector of another length would not fit the store's vector index
+	o.dimensions.CompareAndSwap(0, int64(len(embeddings[0])))
+	dims := o.Dimensions()
+	for _, emb := range embeddings {
+		if len(emb) != dims {
+			return nil, fmt.Errorf("ollama model %s returned a %d-dimension embedding, expected %d", o.model, len(emb), dims)
+		}
+		normalize(emb)
+	}
+
+	return embeddings, nil
+}
+
+// embedAll embeds texts with /api/embed, or one by one with the legacy
+// endpoint if the server is too old for it
+func (o *Ollama) embedAll(ctx context.Context, texts []string) ([][]float32, error) {
+	if !o.legacy.Load() {
+		embeddings, err := o.embed(ctx, texts)
+		if !errors.Is(err, errLegacyAPI) {
+			return embeddings, err
+		}
+		o.legacy.Store(true)
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		emb, err := o.embedLegacy(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = emb
+	}
+
+	return embeddings, nil
+}
+
+// embed calls /api/embed, which accepts a batch of inputs
+func (o *Ollama) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+
+	err := o.post(ctx, "/api/embed", map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama batch embedding error: got %d embeddings for %d inputs", len(resp.Embeddings), len(texts))
+	}
+	for _, emb := range resp.Embeddings {
+		if len(emb) == 0 {
+			return nil, fmt.Errorf("no embedding returned")
+		}
+	}
+
+	return resp.Embeddings, nil
+}
+
+// embedLegacy calls /api/embeddings, available on Ollama versions before 0.3
+func (o *Ollama) embedLegacy(ctx context.Context, text string) ([]float32, error) {
+	var resp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+
+	err := o.post(ctx, "/api/embeddings", map[string]interface{}{
+		"model":  o.model,
+		"prompt": text,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding returned")
+	}
+
+	return resp.Embedding, nil
+}
+
+func (o *Ollama) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama embedding error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("ollama embedding error: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound && path == "/api/embed" && !isModelNotFound(data) {
+		return errLegacyAPI
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("ollama embedding error: %s", apiErr.Error)
+		}
+		return fmt.Errorf("ollama embedding error: %s", resp.Status)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("ollama embedding error: invalid response: %w", err)
+	}
+
+	return nil
+}
+
+// isModelNotFound distinguishes "model not pulled" (also a 404) from a
+// server that lacks the endpoint altogether
+func isModelNotFound(body []byte) bool {
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil {
+		return false
+	}
+	return strings.Contains(apiErr.Error, "model")
+}
+
+// normalize scales a vector to unit length in place
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// Model returns the model name
+func (o *Ollama) Model() string {
+	return o.model
+}
+
+// Dimensions returns the embedding dimensions, or 0 if they weren't given to
+// NewOllama and the server hasn't been reached yet
+func (o *Ollama) Dimensions() int {
+	return int(o.dimensions.Load())
+}
@@ -0,0 +1,318 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeOllama stands in for an Ollama server. Each text embeds to a vector
+// whose first component is its length, so results can be told apart.
+type fakeOllama struct {
+	legacy    bool   // Answer 404 on /api/embed, like Ollama before 0.3
+	status    int    // If set, fail every request with this status
+	errorBody string // Body sent with status
+	dims      int
+	scale     float32 // Multiplies every vector, to check normalization
+	ragged    bool    // Return a longer vector for every text after the first
+
+	down        atomic.Bool // Fail every request while set
+	embedCalls  atomic.Int32
+	legacyCalls atomic.Int32
+}
+
+func (f *fakeOllama) vector(text string) []float32 {
+	v := make([]float32, f.dims)
+	v[0] = float32(len(text)) * f.scale
+	v[1] = f.scale
+	return v
+}
+
+func (f *fakeOllama) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string   `json:"model"`
+		Input  []string `json:"input"`
+		Prompt string   `json:"prompt"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if f.status != 0 {
+		http.Error(w, f.errorBody, f.status)
+		return
+	}
+	if f.down.Load() {
+		http.Error(w, "", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/embed":
+		f.embedCalls.Add(1)
+		if f.legacy {
+			http.NotFound(w, r)
+			return
+		}
+		out := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			out[i] = f.vector(text)
+			if f.ragged && i > 0 {
+				out[i] = append(out[i], 1)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": out})
+	case "/api/embeddings":
+		f.legacyCalls.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"embedding": f.vector(req.Prompt)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// startFakeOllama serves f, filling in its defaults
+func startFakeOllama(t *testing.T, f *fakeOllama) *httptest.Server {
+	t.Helper()
+	if f.dims == 0 {
+		f.dims = 4
+	}
+	if f.scale == 0 {
+		f.scale = 1
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestOllama returns a provider for f that knows its dimension, so that
+// it makes no probe
+func newTestOllama(t *testing.T, f *fakeOllama) *Ollama {
+	t.Helper()
+	srv := startFakeOllama(t, f)
+	return NewOllamaWithClient(srv.URL, "test-model", f.dims, srv.Client())
+}
+
+func TestOllamaEmbedBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      fakeOllama
+		texts       []string
+		embedCalls  int32
+		legacyCalls int32
+		wantErr     string
+	}{
+		{
+			name:       "batch in one request",
+			texts:      []string{"a", "bb", "ccc"},
+			embedCalls: 1,
+		},
+		{
+			name:        "legacy server falls back to one request per text",
+			server:      fakeOllama{legacy: true},
+			texts:       []string{"a", "bb", "ccc"},
+			embedCalls:  1,
+			legacyCalls: 3,
+		},
+		{
+			name:    "error body is reported",
+			server:  fakeOllama{status: http.StatusInternalServerError, errorBody: `{"error":"out of memory"}`},
+			texts:   []string{"a"},
+			wantErr: "out of memory",
+		},
+		{
+			name:    "status is reported without an error body",
+			server:  fakeOllama{status: http.StatusBadGateway, errorBody: "<html>"},
+			texts:   []string{"a"},
+			wantErr: "502",
+		},
+		{
+			name:    "missing model is not mistaken for a legacy server",
+			server:  fakeOllama{status: http.StatusNotFound, errorBody: `{"error":"model \"test-model\" not found, try pulling it first"}`},
+			texts:   []string{"a"},
+			wantErr: "not found",
+		},
+		{
+			name:       "vectors are normalized",
+			server:     fakeOllama{scale: 10},
+			texts:      []string{"abc"},
+			embedCalls: 1,
+		},
+		{
+			name:        "legacy vectors are normalized",
+			server:      fakeOllama{legacy: true, scale: 10},
+			texts:       []string{"abc"},
+			embedCalls:  1,
+			legacyCalls: 1,
+		},
+	}
+
+	for i := range tests {
+		tt := &tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOllama(t, &tt.server)
+
+			got, err := o.EmbedBatch(context.Background(), tt.texts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if o.legacy.Load() {
+					t.Error("switched to the legacy endpoint after an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EmbedBatch: %v", err)
+			}
+
+			if len(got) != len(tt.texts) {
+				t.Fatalf("got %d embeddings for %d texts", len(got), len(tt.texts))
+			}
+			for i, v := range got {
+				if n := norm(v); math.Abs(n-1) > 1e-5 {
+					t.Errorf("embedding %d has norm %f, want 1", i, n)
+				}
+				if want := tt.server.vector(tt.texts[i]); !sameDirection(v, want) {
+					t.Errorf("embedding %d = %v, want the direction of %v", i, v, want)
+				}
+			}
+			if n := tt.server.embedCalls.Load(); n != tt.embedCalls {
+				t.Errorf("/api/embed called %d times, want %d", n, tt.embedCalls)
+			}
+			if n := tt.server.legacyCalls.Load(); n != tt.legacyCalls {
+				t.Errorf("/api/embeddings called %d times, want %d", n, tt.legacyCalls)
+			}
+		})
+	}
+}
+
+func TestOllamaRemembersLegacyServer(t *testing.T) {
+	f := &fakeOllama{legacy: true}
+	o := newTestOllama(t, f)
+
+	for i := 0; i < 3; i++ {
+		if _, err := o.Embed(context.Background(), "text"); err != nil {
+			t.Fatalf("Embed: %v", err)
+		}
+	}
+	if n := f.embedCalls.Load(); n != 1 {
+		t.Errorf("/api/embed tried %d times, want 1", n)
+	}
+}
+
+func TestOllamaDimensions(t *testing.T) {
+	f := &fakeOllama{dims: 7}
+	srv := startFakeOllama(t, f)
+
+	// An unknown dimension is probed when the provider is built
+	o := NewOllamaWithClient(srv.URL, "test-model", 0, srv.Client())
+	if d := o.Dimensions(); d != 7 {
+		t.Errorf("Dimensions after the probe = %d, want 7", d)
+	}
+	if n := f.embedCalls.Load(); n != 1 {
+		t.Errorf("probe made %d requests, want 1", n)
+	}
+
+	// A known one is not
+	known := NewOllamaWithClient(srv.URL, "", 768, srv.Client())
+	if d := known.Dimensions(); d != 768 {
+		t.Errorf("Dimensions given to the constructor = %d, want 768", d)
+	}
+	if n := f.embedCalls.Load(); n != 1 {
+		t.Errorf("constructor with known dimensions made %d requests, want none", n-1)
+	}
+	if m := known.Model(); m != DefaultOllamaModel {
+		t.Errorf("Model = %q, want %q", m, DefaultOllamaModel)
+	}
+
+	// While the server is down, it is learned from the first embedding
+	f.down.Store(true)
+	late := NewOllamaWithClient(srv.URL, "test-model", 0, srv.Client())
+	if d := late.Dimensions(); d != 0 {
+		t.Errorf("Dimensions with the server down = %d, want 0", d)
+	}
+	f.down.Store(false)
+	if _, err := late.Embed(context.Background(), "text"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if d := late.Dimensions(); d != 7 {
+		t.Errorf("Dimensions after embedding = %d, want 7", d)
+	}
+}
+
+func TestOllamaRejectsOtherDimensions(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  fakeOllama
+		known   int // Dimensions given to the constructor
+		texts   []string
+		wantErr string
+	}{
+		{
+			name:    "model returns another dimension than the known one",
+			server:  fakeOllama{dims: 4},
+			known:   5,
+			texts:   []string{"a"},
+			wantErr: "returned a 4-dimension embedding, expected 5",
+		},
+		{
+			name:    "batch of mixed dimensions",
+			server:  fakeOllama{dims: 4, ragged: true},
+			texts:   []string{"a", "bb"},
+			wantErr: "returned a 5-dimension embedding, expected 4",
+		},
+		{
+			name:    "legacy server returns another dimension than the known one",
+			server:  fakeOllama{dims: 4, legacy: true},
+			known:   3,
+			texts:   []string{"a", "bb"},
+			wantErr: "returned a 4-dimension embedding, expected 3",
+		},
+	}
+
+	for i := range tests {
+		tt := &tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			srv := startFakeOllama(t, &tt.server)
+			o := NewOllamaWithClient(srv.URL, "test-model", tt.known, srv.Client())
+			if tt.known == 0 {
+				// Let the batch, not the probe, set the dimension
+				o.dimensions.Store(0)
+			}
+
+			_, err := o.EmbedBatch(context.Background(), tt.texts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOllamaUnreachable(t *testing.T) {
+	o := NewOllamaWithClient("http://127.0.0.1:1", "test-model", 0, http.DefaultClient)
+	if _, err := o.Embed(context.Background(), "text"); err == nil {
+		t.Fatal("expected an error from an unreachable server")
+	}
+}
+
+func norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
+
+func sameDirection(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return math.Abs(dot/(norm(a)*norm(b))-1) < 1e-5
+}
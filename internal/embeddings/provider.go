@@ -14,6 +14,7 @@ type Provider interface {
 	// Model returns the model name being used
 	Model() string
 
-	// Dimensions returns the embedding vector dimensions
+	// Dimensions returns the embedding vector dimensions, or 0 if the
+	// provider only learns them from its first embedding
 	Dimensions() int
 }
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Tool arguments, decoded from the JSON the client sent
+
+type storeArgs struct {
+	Content  string   `json:"content"`
+	Type     string   `json:"type"`
+	TopicKey string   `json:"topic_key"`
+	Tags     []string `json:"tags"`
+}
+
+type recallArgs struct {
+	Query           string   `json:"query"`
+	Limit           int      `json:"limit"`
+	Type            string   `json:"type"`
+	Tags            []string `json:"tags"`
+	Project         string   `json:"project"`
+	TopicKey        string   `json:"topic_key"`
+	IncludeProposed bool     `json:"include_proposed"`
+	Rerank          bool     `json:"rerank"`
+	MMRLambda       float64  `json:"mmr_lambda"`
+	Explain         bool     `json:"explain"`
+}
+
+type relateArgs struct {
+	FromID   string `json:"from_id"`
+	ToID     string `json:"to_id"`
+	Relation string `json:"relation"`
+	Note     string `json:"note"`
+}
+
+type validateArgs struct {
+	ID    string `json:"id"`
+	Trust string `json:"trust"`
+}
+
+type learnErrorArgs struct {
+	Error    string `json:"error"`
+	Cause    string `json:"cause"`
+	Solution string `json:"solution"`
+	Context  string `json:"context"`
+}
+
+type historyArgs struct {
+	ID  string `json:"id"`
+	Rev int    `json:"rev"`
+}
+
+type getArgs struct {
+	ID string `json:"id"`
+}
+
+type listArgs struct {
+	TopicKey string   `json:"topic_key"`
+	Type     string   `json:"type"`
+	Tags     []string `json:"tags"`
+	Trust    []string `json:"trust"`
+	Project  string   `json:"project"`
+	Limit    int      `json:"limit"`
+}
+
+type updateArgs struct {
+	ID      string   `json:"id"`
+	Content string   `json:"content"`
+	Type    string   `json:"type"`
+	Tags    []string `json:"tags"`
+}
+
+type deleteArgs struct {
+	ID string `json:"id"`
+}
+
+type unrelateArgs struct {
+	ID       string `json:"id"`
+	FromID   string `json:"from_id"`
+	ToID     string `json:"to_id"`
+	Relation string `json:"relation"`
+}
+
+// decodeArgs decodes tool arguments into a struct, so that an argument of
+// the wrong type is reported instead of silently ignored
+func decodeArgs(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	err := json.Unmarshal(raw, v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return fmt.Errorf("arguments must be an object")
+		}
+		return fmt.Errorf("%s must be %s, not %s", typeErr.Field, describeType(typeErr.Type), typeErr.Value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// describeType names a Go type the way the tool schemas do
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "an array of " + strings.TrimPrefix(strings.TrimPrefix(describeType(t.Elem()), "a "), "an ") + "s"
+	default:
+		return t.String()
+	}
+}
+
+// checkEnum fails if a non-empty value is not one of the allowed values
+func checkEnum(name, value string, allowed []string) error {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s, not %q", name, strings.Join(allowed, ", "), value)
+}
@@ -0,0 +1,281 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTP transport settings
+const (
+	httpPath        = "/mcp"
+	sessionHeader   = "Mcp-Session-Id"
+	maxRequestBody  = 4 << 20
+	sessionTTL      = time.Hour        // Sessions unseen for this long are closed
+	sseKeepAlive    = 25 * time.Second // Comment sent on idle streams to keep proxies from closing them
+	sessionBacklog  = 64               // Messages queued per session while its stream is closed
+	shutdownTimeout = 5 * time.Second
+)
+
+// HTTPOptions configures the HTTP transport
+type HTTPOptions struct {
+	Addr  string // Address to listen on, e.g. ":7777"
+	Token string // If set, clients must send "Authorization: Bearer <token>"
+}
+
+// RunHTTP serves any number of clients over the MCP Streamable HTTP transport
+// until ctx is done. Clients POST JSON-RPC messages to /mcp and receive the
+// responses as JSON; server notifications are streamed as server-sent events
+// to clients holding a GET request open on the same path.
+func (s *Server) RunHTTP(ctx context.Context, opts HTTPOptions) error {
+	mux := http.NewServeMux()
+	mux.Handle(httpPath, &httpTransport{server: s, token: opts.Token})
+
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go s.retryWhenIdle(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// httpTransport handles the MCP endpoint
+type httpTransport struct {
+	server *Server
+	token  string
+}
+
+func (t *httpTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !t.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cortex"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !t.allowedOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleStream(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost runs a JSON-RPC message or batch. An initialize request opens a
+// new session; every other message must name an open session.
+func (t *httpTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		http.Error(w, "Failed to read request", http.StatusBadRequest)
+		return
+	}
+
+	var messages []json.RawMessage
+	batch := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+	if batch {
+		err = json.Unmarshal(body, &messages)
+	} else {
+		messages = []json.RawMessage{body}
+	}
+
+	var requests []*Request
+	for _, m := range messages {
+		if err != nil {
+			break
+		}
+		var req Request
+		err = json.Unmarshal(m, &req)
+		requests = append(requests, &req)
+	}
+	if err != nil || len(requests) == 0 {
+		writeJSON(w, http.StatusBadRequest, Response{JSONRPC: "2.0", Error: &Error{Code: -32700, Message: "Parse error"}})
+		return
+	}
+
+	var sess *session
+	if requests[0].Method == "initialize" {
+		if len(requests) > 1 {
+			writeJSON(w, http.StatusBadRequest, Response{JSONRPC: "2.0", Error: &Error{Code: -32600, Message: "initialize must be sent alone"}})
+			return
+		}
+		sess = t.openSession()
+		w.Header().Set(sessionHeader, sess.id)
+	} else if sess = t.requireSession(w, r); sess == nil {
+		return
+	}
+
+	var responses []*Response
+	for _, req := range requests {
+		if req.Method == "" {
+			continue // A response from the client; the server sends no requests
+		}
+		if req.ID == nil {
+			t.server.handle(r.Context(), sess, req)
+			continue
+		}
+
+		if resp := t.server.handle(r.Context(), sess, req); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+
+	switch {
+	case len(responses) == 0:
+		w.WriteHeader(http.StatusAccepted)
+	case batch:
+		writeJSON(w, http.StatusOK, responses)
+	default:
+		writeJSON(w, http.StatusOK, responses[0])
+	}
+}
+
+// handleStream streams a session's notifications as server-sent events until
+// the client disconnects or the session ends
+func (t *httpTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Accept must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess := t.requireSession(w, r)
+	if sess == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sess.done:
+			return
+		case data := <-sess.events:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			t.server.session(sess.id) // An open stream keeps the session alive
+		}
+	}
+}
+
+// handleDelete ends a session at the client's request
+func (t *httpTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+	if !t.server.closeSession(id) {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// openSession starts a session whose messages are queued for its stream.
+// Messages are dropped while the queue is full.
+func (t *httpTransport) openSession() *session {
+	t.server.closeIdleSessions(sessionTTL)
+
+	events := make(chan []byte, sessionBacklog)
+	sess := t.server.openSession(func(data []byte) {
+		select {
+		case events <- data:
+		default:
+		}
+	})
+	sess.events = events
+	return sess
+}
+
+// requireSession returns the session named by the request, or writes an
+// error and returns nil
+func (t *httpTransport) requireSession(w http.ResponseWriter, r *http.Request) *session {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return nil
+	}
+	sess := t.server.session(id)
+	if sess == nil {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return nil
+	}
+	return sess
+}
+
+// authorized checks the bearer token, if one is required
+func (t *httpTransport) authorized(r *http.Request) bool {
+	if t.token == "" {
+		return true
+	}
+	got := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+t.token)) == 1
+}
+
+// allowedOrigin refuses browser requests from other sites, which could
+// otherwise reach a server on localhost (DNS rebinding). With a token,
+// callers have already proven they are allowed.
+func (t *httpTransport) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || t.token != "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
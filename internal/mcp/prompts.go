@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// summarizeContextLimit is the number of existing memories shown to the
+// model when it summarizes what was learned
+const summarizeContextLimit = 10
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type PromptsListResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+type PromptGetParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments"`
+}
+
+type PromptMessage struct {
+	Role    string       `json:"role"`
+	Content ContentBlock `json:"content"`
+}
+
+type PromptGetResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+var prompts = []Prompt{
+	{
+		Name:        "recall_context",
+		Description: "Recall what Cortex remembers that is relevant to a task",
+		Arguments: []PromptArgument{
+			{Name: "task", Description: "The task you are about to work on", Required: true},
+			{Name: "limit", Description: "Maximum number of memories (default: 5)"},
+		},
+	},
+	{
+		Name:        "summarize_learnings",
+		Description: "Summarize what we learned in this conversation and store it in Cortex",
+		Arguments: []PromptArgument{
+			{Name: "topic", Description: "Topic key prefix the learnings belong to (e.g. 'react/')"},
+		},
+	},
+}
+
+func (s *Server) handlePromptsList(req *Request) (interface{}, *Error) {
+	return PromptsListResult{Prompts: prompts}, nil
+}
+
+func (s *Server) handlePromptsGet(ctx context.Context, req *Request) (interface{}, *Error) {
+	var params PromptGetParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &Error{Code: -32602, Message: "Invalid params"}
+	}
+
+	var result *PromptGetResult
+	var err error
+
+	switch params.Name {
+	case "recall_context":
+		result, err = s.promptRecallContext(ctx, params.Arguments)
+	case "summarize_learnings":
+		result, err = s.promptSummarizeLearnings(params.Arguments)
+	default:
+		return nil, &Error{Code: -32602, Message: fmt.Sprintf("Unknown prompt: %s", params.Name)}
+	}
+	if err != nil {
+		return nil, &Error{Code: -32602, Message: err.Error()}
+	}
+
+	return result, nil
+}
+
+func (s *Server) promptRecallContext(ctx context.Context, args map[string]string) (*PromptGetResult, error) {
+	task := strings.TrimSpace(args["task"])
+	if task == "" {
+		return nil, fmt.Errorf("task is required")
+	}
+
+	opts := defaultRecallOptions()
+	if l := args["limit"]; l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("limit must be a positive number")
+		}
+		opts.Limit = n
+	}
+
+	results, err := s.engine.Recall(ctx, task, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recall: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("I'm about to work on this task:\n\n%s\n\n", task))
+	if len(results) == 0 {
+		sb.WriteString("Cortex has no validated memories relevant to it.")
+	} else {
+		sb.WriteString("Here is what Cortex remembers that is relevant. Follow these unless the task says otherwise, and mention any that turn out to be wrong:\n\n")
+		for i, r := range results {
+			sb.WriteString(fmt.Sprintf("[%d] %s (trust: %s, %s)\n", i+1, r.Memory.Type, r.Memory.Trust, memoryURI(&r.Memory)))
+			if r.Memory.TopicKey != "" {
+				sb.WriteString(fmt.Sprintf("Topic: %s\n", r.Memory.TopicKey))
+			}
+			sb.WriteString(fmt.Sprintf("%s\n\n", r.Memory.Content))
+		}
+	}
+
+	return &PromptGetResult{
+		Description: fmt.Sprintf("Memories relevant to: %s", task),
+		Messages: []PromptMessage{{
+			Role:    "user",
+			Content: ContentBlock{Type: "text", Text: strings.TrimSpace(sb.String())},
+		}},
+	}, nil
+}
+
+func (s *Server) promptSummarizeLearnings(args map[string]string) (*PromptGetResult, error) {
+	topic := strings.TrimSpace(args["topic"])
+
+	existing, err := s.engine.List(types.RecallOptions{
+		TopicKey:    topic,
+		TrustLevels: listedTrust,
+		Limit:       summarizeContextLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memories: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Summarize what we learned in this conversation that is worth remembering in future sessions: ")
+	sb.WriteString("fixes that worked, decisions and their reasons, patterns and conventions to follow, and mistakes to avoid. ")
+	sb.WriteString("Leave out anything that only mattered for this conversation.\n\n")
+	sb.WriteString("Store each lesson as its own memory with cortex_store, with a type and a topic key")
+	if topic != "" {
+		sb.WriteString(fmt.Sprintf(" under '%s'", topic))
+	}
+	sb.WriteString(". Use cortex_learn_error for errors and their solutions. ")
+	sb.WriteString("Reuse the topic key of an existing memory to update it instead of storing a near-duplicate.")
+
+	if len(existing) > 0 {
+		sb.WriteString("\n\nCortex already holds these memories")
+		if topic != "" {
+			sb.WriteString(fmt.Sprintf(" under '%s'", topic))
+		}
+		sb.WriteString(":\n\n")
+		for _, m := range existing {
+			sb.WriteString(fmt.Sprintf("- %s", m.Type))
+			if m.TopicKey != "" {
+				sb.WriteString(fmt.Sprintf(" [%s]", m.TopicKey))
+			}
+			sb.WriteString(fmt.Sprintf(": %s\n", firstLine(m)))
+		}
+	}
+
+	return &PromptGetResult{
+		Description: "Summarize and store what we learned",
+		Messages: []PromptMessage{{
+			Role:    "user",
+			Content: ContentBlock{Type: "text", Text: strings.TrimSpace(sb.String())},
+		}},
+	}, nil
+}
@@ -0,0 +1,316 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Resource URIs
+const (
+	memoryURIPrefix = "cortex://memory/"
+	topicURIPrefix  = "cortex://topic/"
+)
+
+// resourcesPageSize is the number of resources returned per resources/list page
+const resourcesPageSize = 100
+
+// resourceNotFound is the MCP error code for an unknown resource URI
+const resourceNotFound = -32002
+
+// listedTrust lists the trust levels of memories exposed as resources;
+// obsolete memories are left out
+var listedTrust = []types.TrustLevel{
+	types.TrustProposed,
+	types.TrustValidated,
+	types.TrustProven,
+	types.TrustDisputed,
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+type ResourcesListResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ResourceTemplatesListResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+type ResourceReadResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+type ResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ListParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Notification is a JSON-RPC message that expects no response
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// memoryURI returns the resource URI of a memory
+func memoryURI(m *types.Memory) string {
+	return memoryURIPrefix + m.ID
+}
+
+// handleResourcesList lists every memory that isn't obsolete, newest first.
+// The cursor is the offset of the page.
+func (s *Server) handleResourcesList(req *Request) (interface{}, *Error) {
+	var params ListParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &Error{Code: -32602, Message: "Invalid params"}
+		}
+	}
+	offset := 0
+	if params.Cursor != "" {
+		n, err := strconv.Atoi(params.Cursor)
+		if err != nil || n < 0 {
+			return nil, &Error{Code: -32602, Message: "Invalid cursor"}
+		}
+		offset = n
+	}
+
+	memories, err := s.engine.List(types.RecallOptions{TrustLevels: listedTrust})
+	if err != nil {
+		return nil, &Error{Code: -32603, Message: fmt.Sprintf("Failed to list memories: %v", err)}
+	}
+
+	result := ResourcesListResult{Resources: []Resource{}}
+	for i := offset; i < len(memories) && i < offset+resourcesPageSize; i++ {
+		m := memories[i]
+		result.Resources = append(result.Resources, Resource{
+			URI:         memoryURI(m),
+			Name:        resourceName(m),
+			Description: fmt.Sprintf("%s memory, trust: %s", m.Type, m.Trust),
+			MimeType:    "text/markdown",
+		})
+	}
+	if offset+resourcesPageSize < len(memories) {
+		result.NextCursor = strconv.Itoa(offset + resourcesPageSize)
+	}
+
+	return result, nil
+}
+
+func (s *Server) handleResourceTemplatesList(req *Request) (interface{}, *Error) {
+	return ResourceTemplatesListResult{ResourceTemplates: []ResourceTemplate{
+		{
+			URITemplate: memoryURIPrefix + "{id}",
+			Name:        "Memory",
+			Description: "A memory with its metadata and relations",
+			MimeType:    "text/markdown",
+		},
+		{
+			URITemplate: topicURIPrefix + "{key}",
+			Name:        "Topic",
+			Description: "The memory stored under a topic key, or every memory under a topic prefix (e.g. 'react/')",
+			MimeType:    "text/markdown",
+		},
+	}}, nil
+}
+
+func (s *Server) handleResourcesRead(req *Request) (interface{}, *Error) {
+	var params ResourceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return nil, &Error{Code: -32602, Message: "Invalid params: uri is required"}
+	}
+
+	memories, err := s.readResource(params.URI)
+	if err != nil {
+		return nil, &Error{Code: -32603, Message: fmt.Sprintf("Failed to read resource: %v", err)}
+	}
+	if len(memories) == 0 {
+		return nil, &Error{Code: resourceNotFound, Message: fmt.Sprintf("Resource not found: %s", params.URI)}
+	}
+
+	var sb strings.Builder
+	for i, m := range memories {
+		if i > 0 {
+			sb.WriteString("\n---\n\n")
+		}
+		s.writeMemory(&sb, m)
+	}
+
+	return ResourceReadResult{Contents: []ResourceContents{{
+		URI:      params.URI,
+		MimeType: "text/markdown",
+		Text:     sb.String(),
+	}}}, nil
+}
+
+// readResource returns the memories a resource URI refers to
+func (s *Server) readResource(uri string) ([]*types.Memory, error) {
+	switch {
+	case strings.HasPrefix(uri, memoryURIPrefix):
+		memory, err := s.engine.Get(strings.TrimPrefix(uri, memoryURIPrefix))
+		if err != nil || memory == nil {
+			return nil, err
+		}
+		return []*types.Memory{memory}, nil
+
+	case strings.HasPrefix(uri, topicURIPrefix):
+		key := strings.TrimPrefix(uri, topicURIPrefix)
+		if key == "" {
+			return nil, nil
+		}
+		memory, err := s.engine.Resolve(key)
+		if err != nil {
+			return nil, err
+		}
+		if memory != nil && memory.TopicKey == key {
+			return []*types.Memory{memory}, nil
+		}
+		return s.engine.List(types.RecallOptions{TopicKey: key, TrustLevels: listedTrust})
+	}
+	return nil, nil
+}
+
+// writeMemory renders a memory and its relations as markdown
+func (s *Server) writeMemory(sb *strings.Builder, m *types.Memory) {
+	sb.WriteString(fmt.Sprintf("# %s\n\n", resourceName(m)))
+	sb.WriteString(fmt.Sprintf("- ID: %s\n", m.ID))
+	sb.WriteString(fmt.Sprintf("- Type: %s\n", m.Type))
+	sb.WriteString(fmt.Sprintf("- Trust: %s\n", m.Trust))
+	if m.TopicKey != "" {
+		sb.WriteString(fmt.Sprintf("- Topic: %s\n", m.TopicKey))
+	}
+	if len(m.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("- Tags: %s\n", strings.Join(m.Tags, ", ")))
+	}
+	if m.Store != "" {
+		sb.WriteString(fmt.Sprintf("- Store: %s\n", m.Store))
+	}
+	sb.WriteString(fmt.Sprintf("- Updated: %s\n\n", m.UpdatedAt.Format("2006-01-02 15:04")))
+	sb.WriteString(m.Content)
+	sb.WriteString("\n")
+
+	relations, err := s.engine.GetRelations(m.ID)
+	if err != nil || len(relations) == 0 {
+		return
+	}
+	sb.WriteString("\n## Relations\n\n")
+	for _, r := range relations {
+		if r.FromID == m.ID {
+			sb.WriteString(fmt.Sprintf("- %s → %s%s", r.Type, memoryURIPrefix, r.ToID))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s ← %s%s", r.Type, memoryURIPrefix, r.FromID))
+		}
+		if r.Note != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", r.Note))
+		}
+		sb.WriteString("\n")
+	}
+}
+
+// resourceName is a memory's topic key, or the start of its first line
+func resourceName(m *types.Memory) string {
+	if m.TopicKey != "" {
+		return m.TopicKey
+	}
+	return firstLine(m)
+}
+
+// firstLine returns the start of the first line of a memory's content
+func firstLine(m *types.Memory) string {
+	line := strings.TrimSpace(strings.SplitN(m.Content, "\n", 2)[0])
+	if runes := []rune(line); len(runes) > 60 {
+		line = string(runes[:57]) + "..."
+	}
+	return line
+}
+
+func (s *Server) handleSubscribe(sess *session, req *Request, subscribe bool) (interface{}, *Error) {
+	var params ResourceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return nil, &Error{Code: -32602, Message: "Invalid params: uri is required"}
+	}
+	if !strings.HasPrefix(params.URI, memoryURIPrefix) && !strings.HasPrefix(params.URI, topicURIPrefix) {
+		return nil, &Error{Code: resourceNotFound, Message: fmt.Sprintf("Resource not found: %s", params.URI)}
+	}
+
+	sess.mu.Lock()
+	if subscribe {
+		sess.subscriptions[params.URI] = true
+	} else {
+		delete(sess.subscriptions, params.URI)
+	}
+	sess.mu.Unlock()
+	return struct{}{}, nil
+}
+
+// notifyChanged tells every session that memories changed: the resource list
+// if memories were added, removed or retired (listChanged), and each
+// subscribed resource that covers one of them
+func (s *Server) notifyChanged(listChanged bool, memories ...*types.Memory) {
+	for _, sess := range s.allSessions() {
+		if listChanged {
+			sess.write(Notification{JSONRPC: "2.0", Method: "notifications/resources/list_changed"})
+		}
+
+		sess.mu.Lock()
+		var updated []string
+		for uri := range sess.subscriptions {
+			for _, m := range memories {
+				if m != nil && covers(uri, m) {
+					updated = append(updated, uri)
+					break
+				}
+			}
+		}
+		sess.mu.Unlock()
+
+		for _, uri := range updated {
+			sess.write(Notification{
+				JSONRPC: "2.0",
+				Method:  "notifications/resources/updated",
+				Params:  ResourceParams{URI: uri},
+			})
+		}
+	}
+}
+
+// covers reports whether reading a resource URI includes a memory
+func covers(uri string, m *types.Memory) bool {
+	if uri == memoryURI(m) {
+		return true
+	}
+	key := strings.TrimPrefix(uri, topicURIPrefix)
+	return key != uri && key != "" && strings.HasPrefix(m.TopicKey, key)
+}
+
+// memory looks up a memory for a change notification, or returns nil
+func (s *Server) memory(id string) *types.Memory {
+	m, _ := s.engine.Get(id)
+	return m
+}
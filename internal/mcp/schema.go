@@ -0,0 +1,223 @@
+package mcp
+
+import "github.com/constantino-dev/cortex/pkg/types"
+
+// Values accepted for enumerated fields
+var (
+	memoryTypes   = []string{"general", "error", "pattern", "decision", "context", "procedure"}
+	trustLevels   = []string{"proposed", "validated", "proven", "disputed", "obsolete"}
+	relationTypes = []string{"causes", "solves", "replaces", "requires", "related_to", "part_of", "contradicts"}
+	storeActions  = []string{"created", "updated", "refused", "merged", "revised", "related"}
+)
+
+// RecallOutput is the structured result of cortex_recall
+type RecallOutput struct {
+	Results []RecallHit `json:"results"`
+}
+
+// RecallHit is a recall result together with its memory's relations
+type RecallHit struct {
+	types.SearchResult
+	Relations []*types.Relation `json:"relations,omitempty"`
+}
+
+// RelateOutput is the structured result of cortex_relate
+type RelateOutput struct {
+	Relation *types.Relation `json:"relation"`
+}
+
+// ValidateOutput is the structured result of cortex_validate and cortex_update
+type ValidateOutput struct {
+	Memory *types.Memory `json:"memory"`
+}
+
+// HistoryOutput is the structured result of cortex_history
+type HistoryOutput struct {
+	Memory    *types.Memory     `json:"memory"`
+	Revisions []*types.Revision `json:"revisions"`
+}
+
+// GetOutput is the structured result of cortex_get
+type GetOutput struct {
+	Memory    *types.Memory     `json:"memory"`
+	Relations []*types.Relation `json:"relations,omitempty"`
+}
+
+// ListOutput is the structured result of cortex_list
+type ListOutput struct {
+	Memories []*types.Memory `json:"memories"`
+}
+
+// DeleteOutput is the structured result of cortex_delete
+type DeleteOutput struct {
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted"`
+}
+
+// StatsOutput is the structured result of cortex_stats
+type StatsOutput struct {
+	Stats map[string]int `json:"stats"`
+}
+
+// Output schemas of the tools, matching the JSON encoding of the types above
+
+func memorySchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":           map[string]interface{}{"type": "string"},
+			"content":      map[string]interface{}{"type": "string"},
+			"type":         map[string]interface{}{"type": "string", "enum": memoryTypes},
+			"topic_key":    map[string]interface{}{"type": "string"},
+			"tags":         map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"trust":        map[string]interface{}{"type": "string", "enum": trustLevels},
+			"metadata":     map[string]interface{}{"type": "object"},
+			"created_at":   map[string]interface{}{"type": "string", "format": "date-time"},
+			"updated_at":   map[string]interface{}{"type": "string", "format": "date-time"},
+			"access_count": map[string]interface{}{"type": "integer"},
+			"store":        map[string]interface{}{"type": "string", "description": "Store the memory was read from"},
+		},
+		"required": []string{"id", "content", "type", "trust"},
+	}
+}
+
+func relationSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":         map[string]interface{}{"type": "string"},
+			"from_id":    map[string]interface{}{"type": "string"},
+			"to_id":      map[string]interface{}{"type": "string"},
+			"type":       map[string]interface{}{"type": "string", "enum": relationTypes},
+			"note":       map[string]interface{}{"type": "string"},
+			"created_at": map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+		"required": []string{"id", "from_id", "to_id", "type"},
+	}
+}
+
+func revisionSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory_id":  map[string]interface{}{"type": "string"},
+			"rev":        map[string]interface{}{"type": "integer"},
+			"content":    map[string]interface{}{"type": "string"},
+			"type":       map[string]interface{}{"type": "string", "enum": memoryTypes},
+			"tags":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"trust":      map[string]interface{}{"type": "string", "enum": trustLevels},
+			"author":     map[string]interface{}{"type": "string"},
+			"created_at": map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+		"required": []string{"memory_id", "rev", "content", "type", "trust"},
+	}
+}
+
+func recallOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"results": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"memory":      memorySchema(),
+						"score":       map[string]interface{}{"type": "number", "minimum": 0, "description": "Relevance scaled by the store's weight, so it can exceed 1"},
+						"match_type":  map[string]interface{}{"type": "string", "enum": []string{"semantic", "keyword", "hybrid"}},
+						"explanation": map[string]interface{}{"type": "object", "description": "Score breakdown, if explain was set"},
+						"relations":   map[string]interface{}{"type": "array", "items": relationSchema()},
+					},
+					"required": []string{"memory", "score", "match_type"},
+				},
+			},
+		},
+		"required": []string{"results"},
+	}
+}
+
+func storeOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory":     memorySchema(),
+			"action":     map[string]interface{}{"type": "string", "enum": storeActions},
+			"duplicate":  memorySchema(),
+			"similarity": map[string]interface{}{"type": "number", "description": "Cosine similarity to the duplicate"},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func relateOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"relation": relationSchema()},
+		"required":   []string{"relation"},
+	}
+}
+
+func validateOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"memory": memorySchema()},
+		"required":   []string{"memory"},
+	}
+}
+
+func historyOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory":    memorySchema(),
+			"revisions": map[string]interface{}{"type": "array", "items": revisionSchema()},
+		},
+		"required": []string{"memory", "revisions"},
+	}
+}
+
+func getOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memory":    memorySchema(),
+			"relations": map[string]interface{}{"type": "array", "items": relationSchema()},
+		},
+		"required": []string{"memory"},
+	}
+}
+
+func listOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"memories": map[string]interface{}{"type": "array", "items": memorySchema()},
+		},
+		"required": []string{"memories"},
+	}
+}
+
+func deleteOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":      map[string]interface{}{"type": "string"},
+			"deleted": map[string]interface{}{"type": "boolean"},
+		},
+		"required": []string{"id", "deleted"},
+	}
+}
+
+func statsOutputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"stats": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "integer"},
+				"description":          "Counts summed over all stores",
+			},
+		},
+		"required": []string{"stats"},
+	}
+}
@@ -5,29 +5,44 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/constantino-dev/cortex/internal/core"
 	"github.com/constantino-dev/cortex/pkg/types"
 )
 
-// Server implements the MCP protocol over stdio
+// Server implements the MCP protocol over stdio or HTTP
 type Server struct {
 	engine *core.Engine
 	reader *bufio.Reader
 	writer io.Writer
+
+	workers    chan struct{} // Bounds the requests handled at once
+	writeLock  chan struct{} // Held while changing memories, so changes don't interleave
+	sessionsMu sync.Mutex
+	sessions   map[string]*session
+	lastActive atomic.Int64 // Time of the last request, in Unix nanoseconds
 }
 
 // NewServer creates a new MCP server
 func NewServer(engine *core.Engine) *Server {
-	return &Server{
-		engine: engine,
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
+	s := &Server{
+		engine:    engine,
+		reader:    bufio.NewReader(os.Stdin),
+		writer:    os.Stdout,
+		sessions:  make(map[string]*session),
+		workers:   make(chan struct{}, maxConcurrentRequests),
+		writeLock: make(chan struct{}, 1),
 	}
+	s.lastActive.Store(time.Now().UnixNano())
+	return s
 }
 
 // JSON-RPC structures
@@ -57,7 +72,18 @@ type ServerInfo struct {
 }
 
 type ServerCapabilities struct {
-	Tools map[string]interface{} `json:"tools,omitempty"`
+	Tools     map[string]interface{} `json:"tools,omitempty"`
+	Resources map[string]interface{} `json:"resources,omitempty"`
+	Prompts   map[string]interface{} `json:"prompts,omitempty"`
+}
+
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+type InitializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
 }
 
 type InitializeResult struct {
@@ -67,9 +93,10 @@ type InitializeResult struct {
 }
 
 type Tool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
 }
 
 type ToolsListResult struct {
@@ -77,13 +104,14 @@ type ToolsListResult struct {
 }
 
 type ToolCallParams struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 type ToolResult struct {
-	Content []ContentBlock `json:"content"`
-	IsError bool           `json:"isError,omitempty"`
+	Content           []ContentBlock `json:"content"`
+	StructuredContent interface{}    `json:"structuredContent,omitempty"` // Typed result, described by the tool's outputSchema
+	IsError           bool           `json:"isError,omitempty"`
 }
 
 type ContentBlock struct {
@@ -91,8 +119,45 @@ type ContentBlock struct {
 	Text string `json:"text"`
 }
 
-// Run starts the MCP server
+// Request handling limits
+const (
+	maxConcurrentRequests = 8
+	requestTimeout        = 60 * time.Second
+)
+
+// errCancelledByClient is the cause of a request cancelled with
+// notifications/cancelled; no response is sent for it
+var errCancelledByClient = errors.New("request cancelled by client")
+
+// Idle-time retries of failed embeddings
+const (
+	idleDelay          = 30 * time.Second // Silence before the server counts as idle
+	idlePendingLimit   = 20               // Memories retried per idle period
+	idlePendingTimeout = 30 * time.Second
+)
+
+// Run serves a single client over stdio. Requests are handled concurrently
+// by a bounded pool of workers and answered as they finish; notifications are
+// handled in order as they arrive. Stdin is read without waiting for the pool,
+// so cancellations and pings get through while every worker is busy.
+// Embeddings that failed earlier are retried in the background on start and
+// whenever no request arrives for a while.
 func (s *Server) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.retryWhenIdle(ctx)
+
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	var writeMu sync.Mutex
+	sess := s.openSession(func(data []byte) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		fmt.Fprintln(s.writer, string(data))
+	})
+	defer s.closeSession(sess.id)
+
 	for {
 		line, err := s.reader.ReadString('\n')
 		if err != nil {
@@ -106,47 +171,218 @@ func (s *Server) Run() error {
 		if line == "" {
 			continue
 		}
-
 		var req Request
 		if err := json.Unmarshal([]byte(line), &req); err != nil {
-			s.sendError(nil, -32700, "Parse error")
+			sess.write(Response{JSONRPC: "2.0", Error: &Error{Code: -32700, Message: "Parse error"}})
+			continue
+		}
+		if req.ID == nil {
+			s.handle(ctx, sess, &req)
 			continue
 		}
 
-		s.handleRequest(&req)
+		// Register the request before reading on, so that a cancellation
+		// sent right after it finds it
+		run := s.start(ctx, sess, &req)
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			if resp := run(); resp != nil {
+				sess.write(resp)
+			}
+		}()
+	}
+}
+
+// retryWhenIdle retries a few failed embeddings right away, then whenever
+// no request has arrived for a while, until ctx is done
+func (s *Server) retryWhenIdle(ctx context.Context) {
+	s.embedPending()
+
+	ticker := time.NewTicker(idleDelay)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, s.lastActive.Load())) >= idleDelay {
+				s.embedPending()
+			}
+		}
 	}
 }
 
-func (s *Server) handleRequest(req *Request) {
+// embedPending retries a few failed embeddings while the server is idle
+func (s *Server) embedPending() {
+	if s.engine == nil {
+		return
+	}
+	s.writeLock <- struct{}{}
+	defer func() { <-s.writeLock }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), idlePendingTimeout)
+	defer cancel()
+	s.engine.EmbedPending(ctx, core.PendingOptions{Limit: idlePendingLimit, StopOnFailure: true})
+}
+
+// handle runs a request for a session and returns its response, or nil if
+// the request is a notification or was cancelled by the client. Each request
+// runs with its own context, which times out after requestTimeout and is
+// cancelled by notifications/cancelled. Requests wait for a worker once they
+// can be cancelled, so a queued request can be cancelled too; pings don't
+// wait at all.
+func (s *Server) handle(ctx context.Context, sess *session, req *Request) *Response {
+	return s.start(ctx, sess, req)()
+}
+
+// start registers a request, so that it can be cancelled from then on, and
+// returns the function that runs it. A notification is handled right away.
+func (s *Server) start(ctx context.Context, sess *session, req *Request) func() *Response {
+	s.lastActive.Store(time.Now().UnixNano())
+
+	if req.ID == nil {
+		s.dispatch(ctx, sess, req)
+		return func() *Response { return nil }
+	}
+
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	key := sess.track(req.ID, cancelCause)
+
+	return func() *Response {
+		defer cancel()
+		defer sess.untrack(key)
+		return s.run(ctx, sess, req)
+	}
+}
+
+// run waits for a worker and dispatches a request, unless it is cancelled or
+// times out first
+func (s *Server) run(ctx context.Context, sess *session, req *Request) *Response {
+	if req.Method != "ping" {
+		select {
+		case s.workers <- struct{}{}:
+			defer func() { <-s.workers }()
+		case <-ctx.Done():
+		}
+	}
+
+	var result interface{}
+	var rpcErr *Error
+	if ctx.Err() == nil {
+		result, rpcErr = s.dispatch(ctx, sess, req)
+	}
+
+	switch {
+	case context.Cause(ctx) == errCancelledByClient:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		rpcErr = &Error{Code: -32603, Message: fmt.Sprintf("Request timed out after %s", requestTimeout)}
+	case ctx.Err() != nil:
+		rpcErr = &Error{Code: -32603, Message: fmt.Sprintf("Request stopped: %v", ctx.Err())}
+	}
+	if rpcErr != nil {
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func (s *Server) dispatch(ctx context.Context, sess *session, req *Request) (interface{}, *Error) {
 	switch req.Method {
 	case "initialize":
-		s.handleInitialize(req)
+		return s.handleInitialize(req)
 	case "tools/list":
-		s.handleToolsList(req)
+		return s.handleToolsList(req)
 	case "tools/call":
-		s.handleToolsCall(req)
+		return s.handleToolsCall(ctx, req)
+	case "resources/list":
+		return s.handleResourcesList(req)
+	case "resources/templates/list":
+		return s.handleResourceTemplatesList(req)
+	case "resources/read":
+		return s.handleResourcesRead(req)
+	case "resources/subscribe":
+		return s.handleSubscribe(sess, req, true)
+	case "resources/unsubscribe":
+		return s.handleSubscribe(sess, req, false)
+	case "prompts/list":
+		return s.handlePromptsList(req)
+	case "prompts/get":
+		return s.handlePromptsGet(ctx, req)
+	case "ping":
+		return struct{}{}, nil
 	case "notifications/initialized":
 		// Client acknowledged initialization, no response needed
+		return nil, nil
+	case "notifications/cancelled":
+		s.handleCancelled(sess, req)
+		return nil, nil
 	default:
-		s.sendError(req.ID, -32601, fmt.Sprintf("Method not found: %s", req.Method))
+		return nil, &Error{Code: -32601, Message: fmt.Sprintf("Method not found: %s", req.Method)}
 	}
 }
 
-func (s *Server) handleInitialize(req *Request) {
+// handleCancelled stops a request the client no longer needs
+func (s *Server) handleCancelled(sess *session, req *Request) {
+	var params CancelledParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.RequestID == nil {
+		return
+	}
+	sess.cancel(params.RequestID)
+}
+
+// lockWrites waits for the write lock, unless ctx is done first. The caller
+// must call unlockWrites if it returns nil.
+func (s *Server) lockWrites(ctx context.Context) error {
+	select {
+	case s.writeLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) unlockWrites() {
+	<-s.writeLock
+}
+
+// protocolVersions lists the MCP versions the server speaks, newest first
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+func (s *Server) handleInitialize(req *Request) (interface{}, *Error) {
+	var params InitializeParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &Error{Code: -32602, Message: "Invalid params"}
+		}
+	}
+
+	// Answer with the client's version if supported, else the newest
+	version := protocolVersions[0]
+	for _, v := range protocolVersions {
+		if v == params.ProtocolVersion {
+			version = v
+		}
+	}
+
 	result := InitializeResult{
-		ProtocolVersion: "2024-11-05",
+		ProtocolVersion: version,
 		Capabilities: ServerCapabilities{
-			Tools: map[string]interface{}{},
+			Tools:     map[string]interface{}{"listChanged": false},
+			Resources: map[string]interface{}{"subscribe": true, "listChanged": true},
+			Prompts:   map[string]interface{}{"listChanged": false},
 		},
 		ServerInfo: ServerInfo{
 			Name:    "cortex",
 			Version: "0.1.0",
 		},
 	}
-	s.sendResult(req.ID, result)
+	return result, nil
 }
 
-func (s *Server) handleToolsList(req *Request) {
+func (s *Server) handleToolsList(req *Request) (interface{}, *Error) {
 	tools := []Tool{
 		{
 			Name:        "cortex_store",
@@ -160,7 +396,7 @@ func (s *Server) handleToolsList(req *Request) {
 					},
 					"type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"general", "error", "pattern", "decision", "context", "procedure"},
+						"enum":        memoryTypes,
 						"description": "Type of memory",
 						"default":     "general",
 					},
@@ -176,6 +412,7 @@ func (s *Server) handleToolsList(req *Request) {
 				},
 				"required": []string{"content"},
 			},
+			OutputSchema: storeOutputSchema(),
 		},
 		{
 			Name:        "cortex_recall",
@@ -194,17 +431,47 @@ func (s *Server) handleToolsList(req *Request) {
 					},
 					"type": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"general", "error", "pattern", "decision", "context", "procedure"},
+						"enum":        memoryTypes,
 						"description": "Filter by memory type",
 					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only memories with any of these tags",
+					},
+					"project": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by project",
+					},
+					"topic_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by topic key prefix (e.g., 'react/')",
+					},
 					"include_proposed": map[string]interface{}{
 						"type":        "boolean",
 						"description": "Include unvalidated memories",
 						"default":     false,
 					},
+					"rerank": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Rescore the top candidates with the configured reranker for more precise ordering",
+						"default":     false,
+					},
+					"mmr_lambda": map[string]interface{}{
+						"type":        "number",
+						"description": "Diversify results so near-duplicates don't crowd out other context: 1 favors relevance, lower values favor variety (e.g. 0.5). Omit to disable",
+						"minimum":     0,
+						"maximum":     1,
+					},
+					"explain": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Show how each result was scored and how many candidates each filter dropped, to debug retrieval",
+						"default":     false,
+					},
 				},
 				"required": []string{"query"},
 			},
+			OutputSchema: recallOutputSchema(),
 		},
 		{
 			Name:        "cortex_relate",
@@ -222,7 +489,7 @@ func (s *Server) handleToolsList(req *Request) {
 					},
 					"relation": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"causes", "solves", "replaces", "requires", "related_to", "part_of", "contradicts"},
+						"enum":        relationTypes,
 						"description": "Type of relation",
 					},
 					"note": map[string]interface{}{
@@ -232,6 +499,7 @@ func (s *Server) handleToolsList(req *Request) {
 				},
 				"required": []string{"from_id", "to_id", "relation"},
 			},
+			OutputSchema: relateOutputSchema(),
 		},
 		{
 			Name:        "cortex_validate",
@@ -245,13 +513,14 @@ func (s *Server) handleToolsList(req *Request) {
 					},
 					"trust": map[string]interface{}{
 						"type":        "string",
-						"enum":        []string{"proposed", "validated", "proven", "disputed", "obsolete"},
+						"enum":        trustLevels,
 						"description": "New trust level",
 						"default":     "validated",
 					},
 				},
 				"required": []string{"id"},
 			},
+			OutputSchema: validateOutputSchema(),
 		},
 		{
 			Name:        "cortex_learn_error",
@@ -278,107 +547,341 @@ func (s *Server) handleToolsList(req *Request) {
 				},
 				"required": []string{"error", "solution"},
 			},
+			OutputSchema: storeOutputSchema(),
+		},
+		{
+			Name:        "cortex_history",
+			Description: "Read the revision history of a memory. Use this to see how a decision or pattern stored under a topic key changed over time.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Memory ID or topic key",
+					},
+					"rev": map[string]interface{}{
+						"type":        "integer",
+						"description": "Return only this revision (default: all revisions)",
+					},
+				},
+				"required": []string{"id"},
+			},
+			OutputSchema: historyOutputSchema(),
+		},
+		{
+			Name:        "cortex_get",
+			Description: "Read a memory by ID or topic key, with its metadata and relations.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Memory ID or topic key",
+					},
+				},
+				"required": []string{"id"},
+			},
+			OutputSchema: getOutputSchema(),
+		},
+		{
+			Name:        "cortex_list",
+			Description: "List memories without a search query, newest first. Use this to browse everything under a topic key prefix (e.g., 'react/').",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"topic_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by topic key prefix (e.g., 'react/')",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        memoryTypes,
+						"description": "Filter by memory type",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only memories with any of these tags",
+					},
+					"trust": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": trustLevels},
+						"description": "Only memories with these trust levels (default: all but obsolete)",
+					},
+					"project": map[string]interface{}{
+						"type":        "string",
+						"description": "Filter by project",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of memories",
+						"default":     defaultListLimit,
+						"minimum":     1,
+					},
+				},
+			},
+			OutputSchema: listOutputSchema(),
+		},
+		{
+			Name:        "cortex_update",
+			Description: "Edit a memory in place. Use this to correct its content or retag it; the previous version is kept in its history.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Memory ID or topic key",
+					},
+					"content": map[string]interface{}{
+						"type":        "string",
+						"description": "New content",
+					},
+					"type": map[string]interface{}{
+						"type":        "string",
+						"enum":        memoryTypes,
+						"description": "New memory type",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "New tags, replacing the current ones",
+					},
+				},
+				"required": []string{"id"},
+			},
+			OutputSchema: validateOutputSchema(),
+		},
+		{
+			Name:        "cortex_delete",
+			Description: "Delete a memory along with its relations and history. Use this to remove a memory that is wrong; to retire one that is merely outdated, validate it as obsolete instead.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Memory ID or topic key",
+					},
+				},
+				"required": []string{"id"},
+			},
+			OutputSchema: deleteOutputSchema(),
+		},
+		{
+			Name:        "cortex_unrelate",
+			Description: "Remove a relation between two memories, by relation ID or by its endpoints.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Relation ID",
+					},
+					"from_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Source memory ID, if id is not given",
+					},
+					"to_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Target memory ID, if id is not given",
+					},
+					"relation": map[string]interface{}{
+						"type":        "string",
+						"enum":        relationTypes,
+						"description": "Type of relation, to pick one of several between the same memories",
+					},
+				},
+			},
+			OutputSchema: relateOutputSchema(),
+		},
+		{
+			Name:        "cortex_stats",
+			Description: "Show how many memories, relations and embeddings Cortex holds.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+			OutputSchema: statsOutputSchema(),
 		},
 	}
 
-	s.sendResult(req.ID, ToolsListResult{Tools: tools})
+	return ToolsListResult{Tools: tools}, nil
+}
+
+// writeTools lists the tools that change memories
+var writeTools = map[string]bool{
+	"cortex_store":       true,
+	"cortex_relate":      true,
+	"cortex_validate":    true,
+	"cortex_learn_error": true,
+	"cortex_update":      true,
+	"cortex_delete":      true,
+	"cortex_unrelate":    true,
 }
 
-func (s *Server) handleToolsCall(req *Request) {
+func (s *Server) handleToolsCall(ctx context.Context, req *Request) (interface{}, *Error) {
 	var params ToolCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
-		s.sendError(req.ID, -32602, "Invalid params")
-		return
+		return nil, &Error{Code: -32602, Message: "Invalid params"}
+	}
+
+	if writeTools[params.Name] {
+		if err := s.lockWrites(ctx); err != nil {
+			return nil, &Error{Code: -32603, Message: fmt.Sprintf("Request stopped: %v", err)}
+		}
+		defer s.unlockWrites()
 	}
 
-	ctx := context.Background()
-	var result string
+	var text string
+	var structured interface{}
 	var isError bool
 
 	switch params.Name {
 	case "cortex_store":
-		result, isError = s.toolStore(ctx, params.Arguments)
+		text, structured, isError = s.toolStore(ctx, params.Arguments)
 	case "cortex_recall":
-		result, isError = s.toolRecall(ctx, params.Arguments)
+		text, structured, isError = s.toolRecall(ctx, params.Arguments)
 	case "cortex_relate":
-		result, isError = s.toolRelate(ctx, params.Arguments)
+		text, structured, isError = s.toolRelate(ctx, params.Arguments)
 	case "cortex_validate":
-		result, isError = s.toolValidate(ctx, params.Arguments)
+		text, structured, isError = s.toolValidate(ctx, params.Arguments)
 	case "cortex_learn_error":
-		result, isError = s.toolLearnError(ctx, params.Arguments)
+		text, structured, isError = s.toolLearnError(ctx, params.Arguments)
+	case "cortex_history":
+		text, structured, isError = s.toolHistory(ctx, params.Arguments)
+	case "cortex_get":
+		text, structured, isError = s.toolGet(ctx, params.Arguments)
+	case "cortex_list":
+		text, structured, isError = s.toolList(ctx, params.Arguments)
+	case "cortex_update":
+		text, structured, isError = s.toolUpdate(ctx, params.Arguments)
+	case "cortex_delete":
+		text, structured, isError = s.toolDelete(ctx, params.Arguments)
+	case "cortex_unrelate":
+		text, structured, isError = s.toolUnrelate(ctx, params.Arguments)
+	case "cortex_stats":
+		text, structured, isError = s.toolStats(ctx, params.Arguments)
 	default:
-		s.sendError(req.ID, -32601, fmt.Sprintf("Unknown tool: %s", params.Name))
-		return
+		return nil, &Error{Code: -32601, Message: fmt.Sprintf("Unknown tool: %s", params.Name)}
 	}
 
-	s.sendResult(req.ID, ToolResult{
-		Content: []ContentBlock{{Type: "text", Text: result}},
+	result := ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: text}},
 		IsError: isError,
-	})
+	}
+	if !isError {
+		result.StructuredContent = structured
+	}
+	return result, nil
 }
 
-func (s *Server) toolStore(ctx context.Context, args map[string]interface{}) (string, bool) {
-	content, _ := args["content"].(string)
-	if content == "" {
-		return "Error: content is required", true
+func (s *Server) toolStore(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args storeArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.Content == "" {
+		return "Error: content is required", nil, true
+	}
+	if err := checkEnum("type", args.Type, memoryTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
 	}
 
 	opts := types.StoreOptions{
-		Source: "agent:mcp",
-		Trust:  types.TrustProposed,
+		Type:     types.MemoryType(args.Type),
+		TopicKey: args.TopicKey,
+		Tags:     args.Tags,
+		Source:   "agent:mcp",
+		Trust:    types.TrustProposed,
 	}
 
-	if t, ok := args["type"].(string); ok {
-		opts.Type = types.MemoryType(t)
+	result, err := s.engine.Store(ctx, args.Content, opts)
+	if err != nil {
+		return fmt.Sprintf("Error storing memory: %v", err), nil, true
 	}
-	if tk, ok := args["topic_key"].(string); ok {
-		opts.TopicKey = tk
-	}
-	if tags, ok := args["tags"].([]interface{}); ok {
-		for _, tag := range tags {
-			if t, ok := tag.(string); ok {
-				opts.Tags = append(opts.Tags, t)
-			}
-		}
+	s.notifyStored(result)
+	if result.Duplicate != nil && result.Action != types.StoreRelated {
+		return describeDuplicate(result), result, false
 	}
 
-	memory, err := s.engine.Store(ctx, content, opts)
-	if err != nil {
-		return fmt.Sprintf("Error storing memory: %v", err), true
+	text := fmt.Sprintf("Stored memory with ID: %s (topic: %s)", result.Memory.ID, result.Memory.TopicKey)
+	if result.Duplicate != nil {
+		text += "\n" + describeDuplicate(result)
 	}
+	return text, result, false
+}
 
-	return fmt.Sprintf("Stored memory with ID: %s (topic: %s)", memory.ID, memory.TopicKey), false
+// notifyStored tells the client which resources a store changed
+func (s *Server) notifyStored(result *types.StoreResult) {
+	switch result.Action {
+	case types.StoreRefused:
+	case types.StoreCreated, types.StoreRelated:
+		s.notifyChanged(true, result.Memory, result.Duplicate)
+	default:
+		s.notifyChanged(false, result.Memory)
+	}
 }
 
-func (s *Server) toolRecall(ctx context.Context, args map[string]interface{}) (string, bool) {
-	query, _ := args["query"].(string)
-	if query == "" {
-		return "Error: query is required", true
+// describeDuplicate tells the agent which memory its content collided with
+// and what was done about it
+func describeDuplicate(result *types.StoreResult) string {
+	dup := result.Duplicate
+	var what string
+	switch result.Action {
+	case types.StoreRefused:
+		what = "Not stored: it nearly duplicates an existing memory."
+	case types.StoreMerged:
+		what = "Not stored: it nearly duplicates an existing memory, which was bumped instead."
+	case types.StoreRevised:
+		what = "Stored as a new revision of the existing memory it nearly duplicates."
+	default:
+		what = "Related to an existing memory it nearly duplicates."
 	}
+	return fmt.Sprintf("%s\nExisting memory: %s (%s, trust: %s, similarity %.0f%%)\nContent: %s",
+		what, dup.ID, dup.Type, dup.Trust, result.Similarity*100, dup.Content)
+}
 
-	opts := types.RecallOptions{
-		Limit:       5,
-		MinScore:    0.3,
-		TrustLevels: []types.TrustLevel{types.TrustValidated, types.TrustProven},
+func (s *Server) toolRecall(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args recallArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.Query == "" {
+		return "Error: query is required", nil, true
+	}
+	if err := checkEnum("type", args.Type, memoryTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
 	}
 
-	if limit, ok := args["limit"].(float64); ok {
-		opts.Limit = int(limit)
+	opts := defaultRecallOptions()
+	if args.Limit > 0 {
+		opts.Limit = args.Limit
 	}
-	if t, ok := args["type"].(string); ok {
-		opts.Types = []types.MemoryType{types.MemoryType(t)}
+	if args.Type != "" {
+		opts.Types = []types.MemoryType{types.MemoryType(args.Type)}
 	}
-	if includeProposed, ok := args["include_proposed"].(bool); ok && includeProposed {
+	if args.IncludeProposed {
 		opts.TrustLevels = append(opts.TrustLevels, types.TrustProposed)
 	}
+	opts.Tags = args.Tags
+	opts.Project = args.Project
+	opts.TopicKey = args.TopicKey
+	opts.Rerank = args.Rerank
+	opts.MMRLambda = args.MMRLambda
+	opts.Explain = args.Explain
 
-	results, err := s.engine.Recall(ctx, query, opts)
+	results, err := s.engine.Recall(ctx, args.Query, opts)
 	if err != nil {
-		return fmt.Sprintf("Error searching: %v", err), true
+		return fmt.Sprintf("Error searching: %v", err), nil, true
+	}
+
+	output := RecallOutput{Results: make([]RecallHit, 0, len(results))}
+	for _, r := range results {
+		relations, _ := s.engine.GetRelations(r.Memory.ID)
+		output.Results = append(output.Results, RecallHit{SearchResult: r, Relations: relations})
 	}
 
 	if len(results) == 0 {
-		return "No relevant memories found.", false
+		return "No relevant memories found.", output, false
 	}
 
 	var sb strings.Builder
@@ -391,65 +894,129 @@ func (s *Server) toolRecall(ctx context.Context, args map[string]interface{}) (s
 		if r.Memory.TopicKey != "" {
 			sb.WriteString(fmt.Sprintf("Topic: %s\n", r.Memory.TopicKey))
 		}
-		sb.WriteString(fmt.Sprintf("Content: %s\n\n", r.Memory.Content))
+		sb.WriteString(fmt.Sprintf("Content: %s\n", r.Memory.Content))
+		if r.Explanation != nil {
+			writeExplanation(&sb, r)
+		}
+		sb.WriteString("\n")
+	}
+
+	if ex := results[0].Explanation; ex != nil {
+		sb.WriteString("Filters:\n")
+		for _, f := range ex.Filters {
+			sb.WriteString(fmt.Sprintf("- %s=%s dropped %d candidates\n", f.Name, f.Value, f.Dropped))
+		}
 	}
 
-	return sb.String(), false
+	return sb.String(), output, false
+}
+
+// defaultRecallOptions returns the options agents recall with unless they
+// override them
+func defaultRecallOptions() types.RecallOptions {
+	return types.RecallOptions{
+		Limit:       5,
+		MinScore:    0.3,
+		TrustLevels: []types.TrustLevel{types.TrustValidated, types.TrustProven},
+	}
 }
 
-func (s *Server) toolRelate(ctx context.Context, args map[string]interface{}) (string, bool) {
-	fromID, _ := args["from_id"].(string)
-	toID, _ := args["to_id"].(string)
-	relType, _ := args["relation"].(string)
-	note, _ := args["note"].(string)
+// writeExplanation renders a result's score breakdown
+func writeExplanation(sb *strings.Builder, r types.SearchResult) {
+	ex := r.Explanation
+	sb.WriteString(fmt.Sprintf("Explanation: %s match, score %.3f\n", r.MatchType, r.Score))
+	if ex.Distance != nil {
+		sb.WriteString(fmt.Sprintf("- vector distance %.3f, similarity %.2f", *ex.Distance, ex.Semantic))
+		if ex.SemanticRank > 0 {
+			sb.WriteString(fmt.Sprintf(", rank %d", ex.SemanticRank))
+		}
+		sb.WriteString("\n")
+	}
+	if ex.KeywordRank > 0 {
+		sb.WriteString(fmt.Sprintf("- FTS rank %d, bm25 %.3g, keyword boost %.2f\n", ex.KeywordRank, ex.BM25, ex.Keyword))
+	}
+	if ex.StoreWeight != 1 {
+		sb.WriteString(fmt.Sprintf("- store weight %.2f\n", ex.StoreWeight))
+	}
+	for _, f := range ex.Factors {
+		sb.WriteString(fmt.Sprintf("- %s %.2f adds %.3f\n", f.Name, f.Value, f.Contribution))
+	}
+}
 
-	if fromID == "" || toID == "" || relType == "" {
-		return "Error: from_id, to_id, and relation are required", true
+func (s *Server) toolRelate(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args relateArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.FromID == "" || args.ToID == "" || args.Relation == "" {
+		return "Error: from_id, to_id, and relation are required", nil, true
+	}
+	if err := checkEnum("relation", args.Relation, relationTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
 	}
 
-	relation, err := s.engine.Relate(fromID, toID, types.RelationType(relType), note)
+	relation, err := s.engine.Relate(args.FromID, args.ToID, types.RelationType(args.Relation), args.Note)
 	if err != nil {
-		return fmt.Sprintf("Error creating relation: %v", err), true
+		return fmt.Sprintf("Error creating relation: %v", err), nil, true
 	}
+	s.notifyChanged(false, s.memory(relation.FromID), s.memory(relation.ToID))
 
-	return fmt.Sprintf("Created relation: %s -[%s]-> %s", relation.FromID, relation.Type, relation.ToID), false
+	text := fmt.Sprintf("Created relation %s: %s -[%s]-> %s", relation.ID, relation.FromID, relation.Type, relation.ToID)
+	return text, RelateOutput{Relation: relation}, false
 }
 
-func (s *Server) toolValidate(ctx context.Context, args map[string]interface{}) (string, bool) {
-	id, _ := args["id"].(string)
+func (s *Server) toolValidate(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args validateArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	id := args.ID
 	if id == "" {
-		return "Error: id is required", true
+		return "Error: id is required", nil, true
+	}
+	if err := checkEnum("trust", args.Trust, trustLevels); err != nil {
+		return "Error: " + err.Error(), nil, true
 	}
 
 	trust := types.TrustValidated
-	if t, ok := args["trust"].(string); ok {
-		trust = types.TrustLevel(t)
+	if args.Trust != "" {
+		trust = types.TrustLevel(args.Trust)
 	}
 
 	if err := s.engine.Validate(id, trust); err != nil {
-		return fmt.Sprintf("Error updating trust: %v", err), true
+		return fmt.Sprintf("Error updating trust: %v", err), nil, true
 	}
+	text := fmt.Sprintf("Updated memory %s trust to: %s", id, trust)
 
-	return fmt.Sprintf("Updated memory %s trust to: %s", id, trust), false
-}
+	// The output schema requires the memory, so failing to read it back is an error
+	memory, err := s.engine.Get(id)
+	s.notifyChanged(true, memory)
+	if err == nil && memory == nil {
+		err = fmt.Errorf("memory not found")
+	}
+	if err != nil {
+		return fmt.Sprintf("%s, but failed to read it back: %v", text, err), nil, true
+	}
 
-func (s *Server) toolLearnError(ctx context.Context, args map[string]interface{}) (string, bool) {
-	errorMsg, _ := args["error"].(string)
-	cause, _ := args["cause"].(string)
-	solution, _ := args["solution"].(string)
-	context, _ := args["context"].(string)
+	return text, ValidateOutput{Memory: memory}, false
+}
 
-	if errorMsg == "" || solution == "" {
-		return "Error: error and solution are required", true
+func (s *Server) toolLearnError(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args learnErrorArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.Error == "" || args.Solution == "" {
+		return "Error: error and solution are required", nil, true
 	}
 
 	// Format the content
 	var content strings.Builder
-	content.WriteString(fmt.Sprintf("ERROR: %s\n", errorMsg))
-	if cause != "" {
-		content.WriteString(fmt.Sprintf("CAUSE: %s\n", cause))
+	content.WriteString(fmt.Sprintf("ERROR: %s\n", args.Error))
+	if args.Cause != "" {
+		content.WriteString(fmt.Sprintf("CAUSE: %s\n", args.Cause))
 	}
-	content.WriteString(fmt.Sprintf("SOLUTION: %s", solution))
+	content.WriteString(fmt.Sprintf("SOLUTION: %s", args.Solution))
 
 	opts := types.StoreOptions{
 		Type:   types.TypeError,
@@ -458,37 +1025,79 @@ func (s *Server) toolLearnError(ctx context.Context, args map[string]interface{}
 		Tags:   []string{"learned-error"},
 	}
 
-	if context != "" {
-		opts.ExtraData = map[string]string{"context": context}
+	if args.Context != "" {
+		opts.ExtraData = map[string]string{"context": args.Context}
 	}
 
-	memory, err := s.engine.Store(ctx, content.String(), opts)
+	result, err := s.engine.Store(ctx, content.String(), opts)
 	if err != nil {
-		return fmt.Sprintf("Error storing learned error: %v", err), true
+		return fmt.Sprintf("Error storing learned error: %v", err), nil, true
+	}
+	s.notifyStored(result)
+	if result.Duplicate != nil && result.Action != types.StoreRelated {
+		return describeDuplicate(result), result, false
 	}
 
-	return fmt.Sprintf("Learned error stored with ID: %s. Remember to validate it after confirming the solution works.", memory.ID), false
+	text := fmt.Sprintf("Learned error stored with ID: %s. Remember to validate it after confirming the solution works.", result.Memory.ID)
+	if result.Duplicate != nil {
+		text += "\n" + describeDuplicate(result)
+	}
+	return text, result, false
 }
 
-func (s *Server) sendResult(id interface{}, result interface{}) {
-	resp := Response{
-		JSONRPC: "2.0",
-		ID:      id,
-		Result:  result,
+func (s *Server) toolHistory(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args historyArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	id := args.ID
+	if id == "" {
+		return "Error: id is required", nil, true
 	}
-	s.send(resp)
-}
 
-func (s *Server) sendError(id interface{}, code int, message string) {
-	resp := Response{
-		JSONRPC: "2.0",
-		ID:      id,
-		Error:   &Error{Code: code, Message: message},
+	memory, err := s.engine.Resolve(id)
+	if err != nil {
+		return fmt.Sprintf("Error reading memory: %v", err), nil, true
+	}
+	if memory == nil {
+		return fmt.Sprintf("Error: memory not found: %s", id), nil, true
+	}
+
+	var revisions []*types.Revision
+	if args.Rev != 0 {
+		revision, err := s.engine.Revision(memory.ID, args.Rev)
+		if err != nil {
+			return fmt.Sprintf("Error reading revision: %v", err), nil, true
+		}
+		revisions = []*types.Revision{revision}
+	} else {
+		revisions, err = s.engine.History(memory.ID)
+		if err != nil {
+			return fmt.Sprintf("Error reading history: %v", err), nil, true
+		}
+	}
+
+	output := HistoryOutput{Memory: memory, Revisions: revisions}
+	if output.Revisions == nil {
+		output.Revisions = []*types.Revision{}
+	}
+
+	if len(revisions) == 0 {
+		return fmt.Sprintf("No revisions recorded for %s.", memory.ID), output, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Memory %s", memory.ID))
+	if memory.TopicKey != "" {
+		sb.WriteString(fmt.Sprintf(" (topic: %s)", memory.TopicKey))
+	}
+	sb.WriteString(fmt.Sprintf(" has %d revision(s):\n\n", len(revisions)))
+
+	for _, r := range revisions {
+		sb.WriteString(fmt.Sprintf("[rev %d] %s by %s (%s, trust: %s)\n",
+			r.Rev, r.CreatedAt.Format("2006-01-02 15:04"), r.Author, r.Type, r.Trust))
+		sb.WriteString(fmt.Sprintf("Content: %s\n\n", r.Content))
 	}
-	s.send(resp)
-}
 
-func (s *Server) send(v interface{}) {
-	data, _ := json.Marshal(v)
-	fmt.Fprintln(s.writer, string(data))
+	return sb.String(), output, false
 }
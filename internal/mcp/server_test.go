@@ -0,0 +1,680 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/constantino-dev/cortex/internal/core"
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// stdioClient talks to a server running Run over pipes
+type stdioClient struct {
+	t        *testing.T
+	in       *io.PipeWriter
+	messages chan map[string]interface{}
+}
+
+// startServer runs a server on engine, which may be nil for requests that
+// don't reach it
+func startServer(t *testing.T, engine *core.Engine) (*Server, *stdioClient) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	s := NewServer(engine)
+	s.reader = bufio.NewReader(inR)
+	s.writer = outW
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := s.Run(); err != nil {
+			t.Errorf("Run: %v", err)
+		}
+		outW.Close()
+	}()
+
+	c := &stdioClient{t: t, in: inW, messages: make(chan map[string]interface{}, 16)}
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Errorf("invalid output %q: %v", scanner.Text(), err)
+				continue
+			}
+			c.messages <- msg
+		}
+		close(c.messages)
+	}()
+
+	t.Cleanup(func() {
+		inW.Close()
+		<-done
+	})
+	return s, c
+}
+
+func (c *stdioClient) send(msg string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintln(c.in, msg); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+// next returns the next message from the server, or nil after the timeout
+func (c *stdioClient) next(timeout time.Duration) map[string]interface{} {
+	select {
+	case msg := <-c.messages:
+		return msg
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+// reply returns the next response from the server, skipping notifications,
+// or nil after the timeout
+func (c *stdioClient) reply(timeout time.Duration) map[string]interface{} {
+	deadline := time.Now().Add(timeout)
+	for {
+		msg := c.next(time.Until(deadline))
+		if msg == nil || msg["id"] != nil {
+			return msg
+		}
+	}
+}
+
+// testDimensions is the size of the fake Ollama's vectors
+const testDimensions = 8
+
+// newTestEngine opens an engine on a fresh store, embedding with a fake
+// Ollama that derives a fixed vector from each text. The test is skipped if
+// SQLite was built without FTS5 (go test -tags fts5).
+func newTestEngine(t *testing.T) *core.Engine {
+	t.Helper()
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if r.URL.Path != "/api/embed" || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.NotFound(w, r)
+			return
+		}
+		embeddings := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			embeddings[i] = fakeVector(text)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	t.Cleanup(ollama.Close)
+
+	e, err := core.New(&types.Config{
+		DBPath:            filepath.Join(t.TempDir(), "cortex.db"),
+		EmbeddingProvider: "ollama",
+		OllamaURL:         ollama.URL,
+		OllamaModel:       "fake",
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skipf("SQLite lacks FTS5, run the tests with -tags fts5: %v", err)
+		}
+		t.Fatalf("core.New: %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+// fakeVector returns a unit vector derived from text, unrelated to those of
+// other texts
+func fakeVector(text string) []float32 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	seed := h.Sum64()
+	v := make([]float32, testDimensions)
+	var norm float64
+	for i := range v {
+		seed = seed*6364136223846793005 + 1442695040888963407
+		v[i] = float32(int64(seed>>33)%1000) - 500
+		norm += float64(v[i]) * float64(v[i])
+	}
+	for i := range v {
+		v[i] = float32(float64(v[i]) / math.Sqrt(norm))
+	}
+	return v
+}
+
+// seedMemories stores two validated memories, the first requiring the second,
+// and returns their IDs
+func seedMemories(t *testing.T, e *core.Engine) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	validated := types.StoreOptions{Trust: types.TrustValidated, Tags: []string{"db"}}
+
+	validated.TopicKey = "db/engine"
+	a, err := e.Store(ctx, "we use sqlite for storage", validated)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	validated.TopicKey = "build/cgo"
+	b, err := e.Store(ctx, "the sqlite driver needs a cgo toolchain", validated)
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if _, err := e.Relate(a.Memory.ID, b.Memory.ID, types.RelRequires, ""); err != nil {
+		t.Fatalf("Relate: %v", err)
+	}
+	return a.Memory.ID, b.Memory.ID
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  bool // Serve a store seeded with memories $A and $B, $A requiring $B
+		request string
+		check   func(t *testing.T, resp map[string]interface{})
+	}{
+		{
+			name:    "initialize negotiates a supported version",
+			request: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				result := resp["result"].(map[string]interface{})
+				if result["protocolVersion"] != "2024-11-05" {
+					t.Errorf("protocolVersion = %v", result["protocolVersion"])
+				}
+			},
+		},
+		{
+			name:    "initialize answers an unknown version with the newest",
+			request: `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				result := resp["result"].(map[string]interface{})
+				if result["protocolVersion"] != protocolVersions[0] {
+					t.Errorf("protocolVersion = %v", result["protocolVersion"])
+				}
+			},
+		},
+		{
+			name:    "tools/list has schemas for every tool",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				tools := resp["result"].(map[string]interface{})["tools"].([]interface{})
+				if len(tools) != 12 {
+					t.Errorf("got %d tools, want 12", len(tools))
+				}
+				for _, tool := range tools {
+					tool := tool.(map[string]interface{})
+					if tool["inputSchema"] == nil || tool["outputSchema"] == nil {
+						t.Errorf("%v is missing a schema", tool["name"])
+					}
+				}
+			},
+		},
+		{
+			name:    "ping",
+			request: `{"jsonrpc":"2.0","id":"a","method":"ping"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if resp["id"] != "a" || resp["result"] == nil {
+					t.Errorf("unexpected response %v", resp)
+				}
+			},
+		},
+		{
+			name:    "unknown method",
+			request: `{"jsonrpc":"2.0","id":1,"method":"nope"}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectError(t, resp, -32601)
+			},
+		},
+		{
+			name:    "unknown tool",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"nope"}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectError(t, resp, -32601)
+			},
+		},
+		{
+			name:    "tool arguments of the wrong type",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_recall","arguments":{"query":"x","limit":"5"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectToolError(t, resp, "limit must be an integer")
+			},
+		},
+		{
+			name:    "tool arguments that aren't an object",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_store","arguments":"oops"}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectToolError(t, resp, "arguments must be an object")
+			},
+		},
+		{
+			name:    "tool argument outside its enum",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_list","arguments":{"type":"bogus"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectToolError(t, resp, "type must be one of")
+			},
+		},
+		{
+			name:    "missing required argument",
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_get","arguments":{}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				expectToolError(t, resp, "id is required")
+			},
+		},
+		{
+			name:    "store",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_store","arguments":{"content":"tabs over spaces","type":"decision","tags":["style"]}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if out := structured(t, resp); out["action"] != "created" {
+					t.Errorf("action = %v, want created", out["action"])
+				}
+			},
+		},
+		{
+			name:    "store of a duplicate",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_store","arguments":{"content":"we use sqlite for storage"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				out := structured(t, resp)
+				if out["action"] != "related" || out["duplicate"] == nil || out["similarity"] == nil {
+					t.Errorf("got %v, want a memory related to its duplicate", out)
+				}
+			},
+		},
+		{
+			name:    "recall",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_recall","arguments":{"query":"sqlite","explain":true}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if results := structured(t, resp)["results"].([]interface{}); len(results) != 2 {
+					t.Errorf("got %d results, want 2", len(results))
+				}
+			},
+		},
+		{
+			name:    "relate",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_relate","arguments":{"from_id":"$B","to_id":"$A","relation":"part_of","note":"storage"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				structured(t, resp)
+			},
+		},
+		{
+			name:    "validate",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_validate","arguments":{"id":"$A","trust":"proven"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if memory := structured(t, resp)["memory"].(map[string]interface{}); memory["trust"] != "proven" {
+					t.Errorf("trust = %v, want proven", memory["trust"])
+				}
+			},
+		},
+		{
+			name:    "learn error",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_learn_error","arguments":{"error":"database is locked","cause":"two writers","solution":"enable WAL"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if memory := structured(t, resp)["memory"].(map[string]interface{}); memory["type"] != "error" {
+					t.Errorf("type = %v, want error", memory["type"])
+				}
+			},
+		},
+		{
+			name:    "history",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_history","arguments":{"id":"db/engine"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if revisions := structured(t, resp)["revisions"].([]interface{}); len(revisions) != 1 {
+					t.Errorf("got %d revisions, want 1", len(revisions))
+				}
+			},
+		},
+		{
+			name:    "get",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_get","arguments":{"id":"$A"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if relations := structured(t, resp)["relations"].([]interface{}); len(relations) != 1 {
+					t.Errorf("got %d relations, want 1", len(relations))
+				}
+			},
+		},
+		{
+			name:    "list",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_list","arguments":{"tags":["db"]}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if memories := structured(t, resp)["memories"].([]interface{}); len(memories) != 2 {
+					t.Errorf("got %d memories, want 2", len(memories))
+				}
+			},
+		},
+		{
+			name:    "list of nothing",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_list","arguments":{"type":"error"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if memories := structured(t, resp)["memories"].([]interface{}); len(memories) != 0 {
+					t.Errorf("got %d memories, want none", len(memories))
+				}
+			},
+		},
+		{
+			name:    "update",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_update","arguments":{"id":"$A","content":"we use sqlite in WAL mode"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if memory := structured(t, resp)["memory"].(map[string]interface{}); memory["content"] != "we use sqlite in WAL mode" {
+					t.Errorf("content = %v", memory["content"])
+				}
+			},
+		},
+		{
+			name:    "delete",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_delete","arguments":{"id":"$B"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if out := structured(t, resp); out["deleted"] != true {
+					t.Errorf("got %v", out)
+				}
+			},
+		},
+		{
+			name:    "unrelate",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_unrelate","arguments":{"from_id":"$A","to_id":"$B"}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if relation := structured(t, resp)["relation"].(map[string]interface{}); relation["type"] != "requires" {
+					t.Errorf("removed %v, want the requires relation", relation)
+				}
+			},
+		},
+		{
+			name:    "stats",
+			engine:  true,
+			request: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"cortex_stats","arguments":{}}}`,
+			check: func(t *testing.T, resp map[string]interface{}) {
+				if stats := structured(t, resp)["stats"].(map[string]interface{}); stats["memories"] != float64(2) {
+					t.Errorf("memories = %v, want 2", stats["memories"])
+				}
+			},
+		},
+	}
+
+	schemas := outputSchemas(t)
+	checked := make(map[string]bool)
+	served := false // Whether any test had a store, which needs FTS5
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var engine *core.Engine
+			request := tt.request
+			if tt.engine {
+				engine = newTestEngine(t)
+				served = true
+				a, b := seedMemories(t, engine)
+				request = strings.NewReplacer("$A", a, "$B", b).Replace(request)
+			}
+
+			_, c := startServer(t, engine)
+			c.send(request)
+			resp := c.reply(2 * time.Second)
+			if resp == nil {
+				t.Fatal("no response")
+			}
+			tt.check(t, resp)
+
+			// Structured results must match the tool's output schema
+			result, _ := resp["result"].(map[string]interface{})
+			if out, ok := result["structuredContent"]; ok {
+				var call struct {
+					Params ToolCallParams `json:"params"`
+				}
+				json.Unmarshal([]byte(tt.request), &call)
+				for _, problem := range checkSchema("structuredContent", schemas[call.Params.Name], out) {
+					t.Error(problem)
+				}
+				checked[call.Params.Name] = true
+			}
+		})
+	}
+
+	for name := range schemas {
+		if served && !checked[name] {
+			t.Errorf("structured result of %s not checked against its schema", name)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, c := startServer(t, nil)
+	c.send(`{not json`)
+	resp := c.next(2 * time.Second)
+	if resp == nil {
+		t.Fatal("no response")
+	}
+	expectError(t, resp, -32700)
+}
+
+func TestCancelWhilePoolIsFull(t *testing.T) {
+	s, c := startServer(t, nil)
+
+	// Occupy every worker, as slow tool calls would
+	for i := 0; i < maxConcurrentRequests; i++ {
+		s.workers <- struct{}{}
+	}
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	c.send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	resp := c.next(2 * time.Second)
+	if resp == nil {
+		t.Fatal("ping got no response while the pool was full")
+	}
+	if resp["id"] != float64(2) {
+		t.Fatalf("got response to %v before the ping", resp["id"])
+	}
+
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`)
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < maxConcurrentRequests; i++ {
+		<-s.workers
+	}
+
+	// The cancelled request must not run, or be answered, once a worker frees
+	c.send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	resp = c.next(2 * time.Second)
+	if resp == nil {
+		t.Fatal("no response to ping")
+	}
+	if resp["id"] != float64(3) {
+		t.Fatalf("got response to %v, want only the ping", resp["id"])
+	}
+	if resp := c.next(200 * time.Millisecond); resp != nil {
+		t.Fatalf("unexpected message %v", resp)
+	}
+}
+
+func TestQueuedRequestRunsWhenWorkerFrees(t *testing.T) {
+	s, c := startServer(t, nil)
+
+	for i := 0; i < maxConcurrentRequests; i++ {
+		s.workers <- struct{}{}
+	}
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if resp := c.next(200 * time.Millisecond); resp != nil {
+		t.Fatalf("request ran while the pool was full: %v", resp)
+	}
+
+	<-s.workers
+	resp := c.next(2 * time.Second)
+	if resp == nil || resp["id"] != float64(1) {
+		t.Fatalf("queued request not answered: %v", resp)
+	}
+	for i := 1; i < maxConcurrentRequests; i++ {
+		<-s.workers
+	}
+}
+
+func expectError(t *testing.T, resp map[string]interface{}, code float64) {
+	t.Helper()
+	rpcErr, ok := resp["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected error %v, got %v", code, resp)
+	}
+	if rpcErr["code"] != code {
+		t.Errorf("error code = %v, want %v", rpcErr["code"], code)
+	}
+}
+
+func expectToolError(t *testing.T, resp map[string]interface{}, text string) {
+	t.Helper()
+	result, ok := resp["result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected tool result, got %v", resp)
+	}
+	if result["isError"] != true {
+		t.Errorf("isError = %v", result["isError"])
+	}
+	content := result["content"].([]interface{})[0].(map[string]interface{})
+	if !strings.Contains(content["text"].(string), text) {
+		t.Errorf("text = %q, want it to contain %q", content["text"], text)
+	}
+}
+
+// structured returns the structured content of a successful tool result
+func structured(t *testing.T, resp map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	result, ok := resp["result"].(map[string]interface{})
+	if !ok || result["isError"] == true {
+		t.Fatalf("expected a successful tool result, got %v", resp)
+	}
+	out, ok := result["structuredContent"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("structuredContent = %v, want an object", result["structuredContent"])
+	}
+	return out
+}
+
+// outputSchemas returns the output schema of every tool, decoded from JSON
+// like a client sees it
+func outputSchemas(t *testing.T) map[string]map[string]interface{} {
+	t.Helper()
+	list, _ := NewServer(nil).handleToolsList(nil)
+	data, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Tools []struct {
+			Name         string                 `json:"name"`
+			OutputSchema map[string]interface{} `json:"outputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	schemas := make(map[string]map[string]interface{})
+	for _, tool := range decoded.Tools {
+		schemas[tool.Name] = tool.OutputSchema
+	}
+	return schemas
+}
+
+// checkSchema returns where value, decoded from JSON, breaks schema. It
+// knows the keywords the output schemas use. Object fields the schema
+// doesn't declare are reported too, so the schemas keep up with the types.
+func checkSchema(path string, schema map[string]interface{}, value interface{}) []string {
+	if schema == nil {
+		return []string{path + ": no schema"}
+	}
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("%v is not an object", value)
+			return problems
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				fail("missing %s", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, v := range obj {
+			if prop, ok := properties[name].(map[string]interface{}); ok {
+				problems = append(problems, checkSchema(path+"."+name, prop, v)...)
+			} else if additional != nil {
+				problems = append(problems, checkSchema(path+"."+name, additional, v)...)
+			} else if properties != nil {
+				fail("undeclared field %s", name)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			fail("%v is not an array", value)
+			return problems
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			problems = append(problems, checkSchema(fmt.Sprintf("%s[%d]", path, i), items, v)...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("%v is not a string", value)
+			return problems
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("%q is not a date-time", str)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || (schema["type"] == "integer" && n != math.Trunc(n)) {
+			fail("%v is not of type %s", value, schema["type"])
+			return problems
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			fail("%v is below the minimum %v", n, min)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("%v is not a boolean", value)
+		}
+	default:
+		fail("unknown type %v", schema["type"])
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || allowed == value
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// session is one client's connection: the resources it subscribed to and
+// where its messages go
+type session struct {
+	id     string
+	send   func(data []byte)
+	events chan []byte   // Messages waiting for the client's stream (HTTP only)
+	done   chan struct{} // Closed when the session ends
+
+	mu            sync.Mutex
+	subscriptions map[string]bool                    // Resource URIs the client subscribed to
+	inflight      map[string]context.CancelCauseFunc // Running requests by ID
+	lastSeen      time.Time
+}
+
+// write sends a message to the client
+func (sess *session) write(v interface{}) {
+	data, _ := json.Marshal(v)
+	sess.send(data)
+}
+
+// track records a running request so that it can be cancelled, and returns
+// the key to untrack it with
+func (sess *session) track(id interface{}, cancel context.CancelCauseFunc) string {
+	key := requestKey(id)
+	sess.mu.Lock()
+	sess.inflight[key] = cancel
+	sess.mu.Unlock()
+	return key
+}
+
+func (sess *session) untrack(key string) {
+	sess.mu.Lock()
+	delete(sess.inflight, key)
+	sess.mu.Unlock()
+}
+
+// cancel stops a running request, if it is still running
+func (sess *session) cancel(id interface{}) {
+	sess.mu.Lock()
+	cancel := sess.inflight[requestKey(id)]
+	sess.mu.Unlock()
+	if cancel != nil {
+		cancel(errCancelledByClient)
+	}
+}
+
+// requestKey identifies a request ID, keeping 1 and "1" apart
+func requestKey(id interface{}) string {
+	data, _ := json.Marshal(id)
+	return string(data)
+}
+
+// openSession registers a session whose messages are passed to send
+func (s *Server) openSession(send func(data []byte)) *session {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+
+	sess := &session{
+		id:            hex.EncodeToString(buf),
+		send:          send,
+		done:          make(chan struct{}),
+		subscriptions: make(map[string]bool),
+		inflight:      make(map[string]context.CancelCauseFunc),
+		lastSeen:      time.Now(),
+	}
+
+	s.sessionsMu.Lock()
+	s.sessions[sess.id] = sess
+	s.sessionsMu.Unlock()
+	return sess
+}
+
+// session returns an open session and marks it as seen, or nil
+func (s *Server) session(id string) *session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sess := s.sessions[id]
+	if sess != nil {
+		sess.mu.Lock()
+		sess.lastSeen = time.Now()
+		sess.mu.Unlock()
+	}
+	return sess
+}
+
+// closeSession ends a session. It reports whether the session was open.
+func (s *Server) closeSession(id string) bool {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if ok {
+		delete(s.sessions, id)
+		close(sess.done)
+	}
+	return ok
+}
+
+// closeIdleSessions ends the sessions not seen for longer than ttl
+func (s *Server) closeIdleSessions(ttl time.Duration) {
+	for _, sess := range s.allSessions() {
+		sess.mu.Lock()
+		idle := time.Since(sess.lastSeen) > ttl
+		sess.mu.Unlock()
+		if idle {
+			s.closeSession(sess.id)
+		}
+	}
+}
+
+// allSessions returns the open sessions
+func (s *Server) allSessions() []*session {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	sessions := make([]*session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+// Default number of memories returned by cortex_list
+const defaultListLimit = 20
+
+func (s *Server) toolGet(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args getArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.ID == "" {
+		return "Error: id is required", nil, true
+	}
+
+	memory, err := s.engine.Resolve(args.ID)
+	if err != nil {
+		return fmt.Sprintf("Error reading memory: %v", err), nil, true
+	}
+	if memory == nil {
+		return fmt.Sprintf("Error: memory not found: %s", args.ID), nil, true
+	}
+	relations, err := s.engine.GetRelations(memory.ID)
+	if err != nil {
+		return fmt.Sprintf("Error reading relations: %v", err), nil, true
+	}
+
+	var sb strings.Builder
+	s.writeMemory(&sb, memory)
+	return sb.String(), GetOutput{Memory: memory, Relations: relations}, false
+}
+
+func (s *Server) toolList(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args listArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if err := checkEnum("type", args.Type, memoryTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	for _, t := range args.Trust {
+		if err := checkEnum("trust", t, trustLevels); err != nil {
+			return "Error: " + err.Error(), nil, true
+		}
+	}
+
+	opts := types.RecallOptions{
+		Limit:       defaultListLimit,
+		Tags:        args.Tags,
+		Project:     args.Project,
+		TopicKey:    args.TopicKey,
+		TrustLevels: listedTrust,
+	}
+	if args.Limit > 0 {
+		opts.Limit = args.Limit
+	}
+	if args.Type != "" {
+		opts.Types = []types.MemoryType{types.MemoryType(args.Type)}
+	}
+	if len(args.Trust) > 0 {
+		opts.TrustLevels = nil
+		for _, t := range args.Trust {
+			opts.TrustLevels = append(opts.TrustLevels, types.TrustLevel(t))
+		}
+	}
+
+	memories, err := s.engine.List(opts)
+	if err != nil {
+		return fmt.Sprintf("Error listing memories: %v", err), nil, true
+	}
+
+	output := ListOutput{Memories: memories}
+	if output.Memories == nil {
+		output.Memories = []*types.Memory{}
+	}
+	if len(memories) == 0 {
+		return "No memories found.", output, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Found %d memories:\n\n", len(memories)))
+	for _, m := range memories {
+		sb.WriteString(fmt.Sprintf("- %s [%s, %s] %s\n", m.ID, m.Type, m.Trust, resourceName(m)))
+	}
+	return sb.String(), output, false
+}
+
+func (s *Server) toolUpdate(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args updateArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.ID == "" {
+		return "Error: id is required", nil, true
+	}
+	if args.Content == "" && args.Type == "" && args.Tags == nil {
+		return "Error: content, type or tags is required", nil, true
+	}
+	if err := checkEnum("type", args.Type, memoryTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+
+	memory, err := s.engine.Resolve(args.ID)
+	if err != nil {
+		return fmt.Sprintf("Error reading memory: %v", err), nil, true
+	}
+	if memory == nil {
+		return fmt.Sprintf("Error: memory not found: %s", args.ID), nil, true
+	}
+
+	memory, err = s.engine.Update(ctx, memory.ID, types.UpdateOptions{
+		Content: args.Content,
+		Type:    types.MemoryType(args.Type),
+		Tags:    args.Tags,
+		Source:  "agent:mcp",
+	})
+	if err != nil {
+		return fmt.Sprintf("Error updating memory: %v", err), nil, true
+	}
+	s.notifyChanged(false, memory)
+
+	return fmt.Sprintf("Updated memory %s", memory.ID), ValidateOutput{Memory: memory}, false
+}
+
+func (s *Server) toolDelete(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args deleteArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if args.ID == "" {
+		return "Error: id is required", nil, true
+	}
+
+	memory, err := s.engine.Resolve(args.ID)
+	if err != nil {
+		return fmt.Sprintf("Error reading memory: %v", err), nil, true
+	}
+	if memory == nil {
+		return fmt.Sprintf("Error: memory not found: %s", args.ID), nil, true
+	}
+
+	if err := s.engine.Delete(memory.ID); err != nil {
+		return fmt.Sprintf("Error deleting memory: %v", err), nil, true
+	}
+	s.notifyChanged(true, memory)
+
+	return fmt.Sprintf("Deleted memory %s and its relations", memory.ID), DeleteOutput{ID: memory.ID, Deleted: true}, false
+}
+
+func (s *Server) toolUnrelate(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args unrelateArgs
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+	if err := checkEnum("relation", args.Relation, relationTypes); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+
+	id := args.ID
+	if id == "" {
+		if args.FromID == "" || args.ToID == "" {
+			return "Error: id, or from_id and to_id, is required", nil, true
+		}
+		var err error
+		if id, err = s.findRelation(args); err != nil {
+			return "Error: " + err.Error(), nil, true
+		}
+	}
+
+	relation, err := s.engine.Unrelate(id)
+	if err != nil {
+		return fmt.Sprintf("Error removing relation: %v", err), nil, true
+	}
+	s.notifyChanged(false, s.memory(relation.FromID), s.memory(relation.ToID))
+
+	text := fmt.Sprintf("Removed relation %s: %s -[%s]-> %s", relation.ID, relation.FromID, relation.Type, relation.ToID)
+	return text, RelateOutput{Relation: relation}, false
+}
+
+// findRelation returns the ID of the one relation between two memories,
+// optionally of a given type
+func (s *Server) findRelation(args unrelateArgs) (string, error) {
+	relations, err := s.engine.GetRelations(args.FromID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read relations: %w", err)
+	}
+
+	var matches []*types.Relation
+	for _, r := range relations {
+		if r.FromID != args.FromID || r.ToID != args.ToID {
+			continue
+		}
+		if args.Relation != "" && string(r.Type) != args.Relation {
+			continue
+		}
+		matches = append(matches, r)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no relation from %s to %s", args.FromID, args.ToID)
+	case 1:
+		return matches[0].ID, nil
+	}
+	ids := make([]string, len(matches))
+	for i, r := range matches {
+		ids[i] = fmt.Sprintf("%s (%s)", r.ID, r.Type)
+	}
+	return "", fmt.Errorf("%d relations from %s to %s, pass relation or id: %s",
+		len(matches), args.FromID, args.ToID, strings.Join(ids, ", "))
+}
+
+func (s *Server) toolStats(ctx context.Context, raw json.RawMessage) (string, interface{}, bool) {
+	var args struct{}
+	if err := decodeArgs(raw, &args); err != nil {
+		return "Error: " + err.Error(), nil, true
+	}
+
+	stats, err := s.engine.Stats()
+	if err != nil {
+		return fmt.Sprintf("Error reading stats: %v", err), nil, true
+	}
+
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("Cortex stats:\n\n")
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("- %s: %d\n", k, stats[k]))
+	}
+	return sb.String(), StatsOutput{Stats: stats}, false
+}
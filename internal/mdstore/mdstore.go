@@ -0,0 +1,304 @@
+// Package mdstore serializes memories as Markdown files with a frontmatter
+// header, one file per memory, so a store can be reviewed and merged in git.
+//
+// A file looks like:
+//
+//	---
+//	id: 5f0c1e9a2b7d4c3e8a1f6b2d
+//	type: decision
+//	topic_key: architecture/database
+//	tags: ["storage", "sqlite"]
+//	trust: validated
+//	source: cli
+//	created_at: 2024-05-01T10:00:00Z
+//	updated_at: 2024-05-03T16:20:00Z
+//	relations:
+//	  - {"id":"9a...","type":"replaces","to":"c4...","created_at":"2024-05-03T16:20:00Z"}
+//	---
+//	Use SQLite; we deploy a single node.
+//
+// Scalar values are written bare when that is unambiguous and as JSON strings
+// otherwise; lists and maps are written as JSON, which is also valid YAML.
+// Access counts are deliberately left out: they change on every recall and
+// would turn each search into a diff.
+package mdstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/constantino-dev/cortex/pkg/types"
+)
+
+const delimiter = "---"
+
+// ErrNoFrontmatter is returned by Unmarshal for files that do not start with
+// a frontmatter block, such as a README kept next to the memories
+var ErrNoFrontmatter = errors.New("no frontmatter")
+
+// Document is one memory file: the memory and its outgoing relations
+type Document struct {
+	Memory    types.Memory
+	Relations []types.Relation
+}
+
+// fileRelation is how a relation is written in frontmatter; the source is
+// always the memory the file belongs to
+type fileRelation struct {
+	ID        string             `json:"id"`
+	Type      types.RelationType `json:"type"`
+	To        string             `json:"to"`
+	Note      string             `json:"note,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// unsafeSegment matches characters not allowed in a path segment
+var unsafeSegment = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// PathFor returns the slash-separated path of a memory's file, relative to the
+// sync directory: its topic key (so "react/hooks/rules" becomes
+// react/hooks/rules.md) or, without one, its ID.
+func PathFor(m *types.Memory) string {
+	if m.TopicKey == "" {
+		return m.ID + ".md"
+	}
+
+	var segments []string
+	for _, seg := range strings.Split(m.TopicKey, "/") {
+		seg = strings.Trim(unsafeSegment.ReplaceAllString(seg, "-"), "-.")
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	if len(segments) == 0 {
+		return m.ID + ".md"
+	}
+
+	return path.Join(segments...) + ".md"
+}
+
+// Marshal renders a document as Markdown with frontmatter
+func Marshal(doc *Document) []byte {
+	m := &doc.Memory
+	var b bytes.Buffer
+
+	b.WriteString(delimiter + "\n")
+	writeField(&b, "id", m.ID)
+	writeField(&b, "type", string(m.Type))
+	if m.TopicKey != "" {
+		writeField(&b, "topic_key", m.TopicKey)
+	}
+	if len(m.Tags) > 0 {
+		writeJSON(&b, "tags", m.Tags)
+	}
+	writeField(&b, "trust", string(m.Trust))
+	if m.Metadata.Source != "" {
+		writeField(&b, "source", m.Metadata.Source)
+	}
+	if m.Metadata.Project != "" {
+		writeField(&b, "project", m.Metadata.Project)
+	}
+	if m.Metadata.Author != "" {
+		writeField(&b, "author", m.Metadata.Author)
+	}
+	if len(m.Metadata.ExtraData) > 0 {
+		writeJSON(&b, "extra", m.Metadata.ExtraData)
+	}
+	writeField(&b, "created_at", m.CreatedAt.UTC().Format(time.RFC3339))
+	writeField(&b, "updated_at", m.UpdatedAt.UTC().Format(time.RFC3339))
+
+	if len(doc.Relations) > 0 {
+		b.WriteString("relations:\n")
+		for _, r := range doc.Relations {
+			data, _ := json.Marshal(fileRelation{
+				ID:        r.ID,
+				Type:      r.Type,
+				To:        r.ToID,
+				Note:      r.Note,
+				CreatedAt: r.CreatedAt.UTC(),
+			})
+			b.WriteString("  - ")
+			b.Write(data)
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString(delimiter + "\n")
+	b.WriteString(strings.TrimRight(m.Content, "\n"))
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// Unmarshal parses a file written by Marshal
+func Unmarshal(data []byte) (*Document, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != delimiter {
+		return nil, ErrNoFrontmatter
+	}
+
+	doc := &Document{}
+	m := &doc.Memory
+	inRelations := false
+	closed := false
+	line := 1
+
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == delimiter {
+			closed = true
+			break
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if inRelations && strings.HasPrefix(strings.TrimSpace(text), "- ") {
+			var fr fileRelation
+			item := strings.TrimPrefix(strings.TrimSpace(text), "- ")
+			if err := json.Unmarshal([]byte(item), &fr); err != nil {
+				return nil, fmt.Errorf("line %d: invalid relation: %w", line, err)
+			}
+			doc.Relations = append(doc.Relations, types.Relation{
+				ID:        fr.ID,
+				Type:      fr.Type,
+				ToID:      fr.To,
+				Note:      fr.Note,
+				CreatedAt: fr.CreatedAt,
+			})
+			continue
+		}
+		inRelations = false
+
+		key, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "id":
+			m.ID, err = parseString(value)
+		case "type":
+			var s string
+			s, err = parseString(value)
+			m.Type = types.MemoryType(s)
+		case "topic_key":
+			m.TopicKey, err = parseString(value)
+		case "tags":
+			err = json.Unmarshal([]byte(value), &m.Tags)
+		case "trust":
+			var s string
+			s, err = parseString(value)
+			m.Trust = types.TrustLevel(s)
+		case "source":
+			m.Metadata.Source, err = parseString(value)
+		case "project":
+			m.Metadata.Project, err = parseString(value)
+		case "author":
+			m.Metadata.Author, err = parseString(value)
+		case "extra":
+			err = json.Unmarshal([]byte(value), &m.Metadata.ExtraData)
+		case "created_at":
+			m.CreatedAt, err = parseTime(value)
+		case "updated_at":
+			m.UpdatedAt, err = parseTime(value)
+		case "relations":
+			inRelations = true
+		default:
+			// Unknown keys are ignored so newer files stay readable
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s: %w", line, key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !closed {
+		return nil, fmt.Errorf("unterminated frontmatter")
+	}
+
+	var content strings.Builder
+	for scanner.Scan() {
+		content.WriteString(scanner.Text())
+		content.WriteString("\n")
+	}
+	m.Content = strings.TrimRight(content.String(), "\n")
+
+	if m.ID == "" {
+		return nil, fmt.Errorf("missing id")
+	}
+	if m.Type == "" {
+		m.Type = types.TypeGeneral
+	}
+	if m.Trust == "" {
+		m.Trust = types.TrustProposed
+	}
+	for i := range doc.Relations {
+		doc.Relations[i].FromID = m.ID
+	}
+
+	return doc, nil
+}
+
+// bareValue matches scalars that can be written without quotes
+var bareValue = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:+-]*$`)
+
+func writeField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	b.WriteString(": ")
+	if bareValue.MatchString(value) && !looksLikeNonString(value) {
+		b.WriteString(value)
+	} else {
+		b.WriteString(strconv.Quote(value))
+	}
+	b.WriteString("\n")
+}
+
+func writeJSON(b *bytes.Buffer, key string, value interface{}) {
+	data, _ := json.Marshal(value)
+	b.WriteString(key)
+	b.WriteString(": ")
+	b.Write(data)
+	b.WriteString("\n")
+}
+
+// looksLikeNonString reports whether YAML would read a bare value as
+// something other than a string
+func looksLikeNonString(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func parseString(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) {
+		return strconv.Unquote(value)
+	}
+	return value, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	s, err := parseString(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -40,26 +40,27 @@ type Memory struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	AccessCnt int        `json:"access_count"`
+	Store     string     `json:"store,omitempty"` // Store it was read from (not persisted)
 }
 
 // Metadata holds optional extra information about a memory
 type Metadata struct {
-	Source    string            `json:"source,omitempty"`    // Where this came from
-	Project   string            `json:"project,omitempty"`   // Which project it belongs to
-	Author    string            `json:"author,omitempty"`    // Who created it (human/agent)
-	ExtraData map[string]string `json:"extra,omitempty"`     // Arbitrary key-value pairs
+	Source    string            `json:"source,omitempty"`  // Where this came from
+	Project   string            `json:"project,omitempty"` // Which project it belongs to
+	Author    string            `json:"author,omitempty"`  // Who created it (human/agent)
+	ExtraData map[string]string `json:"extra,omitempty"`   // Arbitrary key-value pairs
 }
 
 // RelationType defines how two memories are connected
 type RelationType string
 
 const (
-	RelCauses     RelationType = "causes"      // A causes B
-	RelSolves     RelationType = "solves"      // A solves B
-	RelReplaces   RelationType = "replaces"    // A replaces B
-	RelRequires   RelationType = "requires"    // A requires B
-	RelRelatedTo  RelationType = "related_to"  // A is related to B
-	RelPartOf     RelationType = "part_of"     // A is part of B
+	RelCauses      RelationType = "causes"      // A causes B
+	RelSolves      RelationType = "solves"      // A solves B
+	RelReplaces    RelationType = "replaces"    // A replaces B
+	RelRequires    RelationType = "requires"    // A requires B
+	RelRelatedTo   RelationType = "related_to"  // A is related to B
+	RelPartOf      RelationType = "part_of"     // A is part of B
 	RelContradicts RelationType = "contradicts" // A contradicts B
 )
 
@@ -73,41 +74,193 @@ type Relation struct {
 	CreatedAt time.Time    `json:"created_at"`
 }
 
+// Revision is a recorded version of a memory's content. A revision is appended
+// each time a memory is created or updated through its topic key.
+type Revision struct {
+	MemoryID  string     `json:"memory_id"`
+	Rev       int        `json:"rev"` // 1-based, increasing
+	Content   string     `json:"content"`
+	Type      MemoryType `json:"type"`
+	Tags      []string   `json:"tags,omitempty"`
+	Trust     TrustLevel `json:"trust"`
+	Author    string     `json:"author,omitempty"` // Source of the change (e.g., "cli", "agent:mcp")
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // SearchResult wraps a memory with its relevance score
 type SearchResult struct {
-	Memory    Memory  `json:"memory"`
-	Score     float64 `json:"score"`      // 0.0 - 1.0
-	MatchType string  `json:"match_type"` // "semantic", "keyword", "hybrid"
+	Memory      Memory       `json:"memory"`
+	Score       float64      `json:"score"`                 // Relevance, 0.0 - 1.0 before the store weight is applied
+	MatchType   string       `json:"match_type"`            // "semantic", "keyword", "hybrid"
+	Explanation *Explanation `json:"explanation,omitempty"` // Set when RecallOptions.Explain is true
+}
+
+// Explanation shows how a recall score was computed
+type Explanation struct {
+	Distance     *float64      `json:"distance,omitempty"`      // L2 distance to the query vector, if the memory has one
+	Semantic     float64       `json:"semantic"`                // Similarity derived from the distance, 0-1
+	SemanticRank int           `json:"semantic_rank,omitempty"` // Position in the vector results (0: not found)
+	KeywordRank  int           `json:"keyword_rank,omitempty"`  // Position in the full-text results (0: not found)
+	BM25         float64       `json:"bm25,omitempty"`          // Raw BM25 relevance
+	Keyword      float64       `json:"keyword"`                 // BM25 relative to the best keyword hit, 0-1
+	StoreWeight  float64       `json:"store_weight"`            // Weight of the store the memory came from
+	Factors      []ScoreFactor `json:"factors"`                 // Contributions add up to the score
+	Filters      []FilterStat  `json:"filters,omitempty"`       // Filters of the recall, shared by all results
+}
+
+// FilterStat reports a recall filter and how many candidates it excluded
+type FilterStat struct {
+	Name    string `json:"name"`    // "type", "trust", "tags", "project", "topic_key" or "min_score"
+	Value   string `json:"value"`   // The filter's setting, e.g. "validated,proven"
+	Dropped int    `json:"dropped"` // Candidates of the unfiltered search that it excluded
+}
+
+// ScoreFactor is one term of a recall score
+type ScoreFactor struct {
+	Name         string  `json:"name"`         // "relevance", "trust", "access", "recency" or "rerank"
+	Value        float64 `json:"value"`        // The factor's own 0-1 value
+	Contribution float64 `json:"contribution"` // What it added to the score
 }
 
 // StoreOptions configures how a memory is stored
 type StoreOptions struct {
-	TopicKey   string            // If set, updates existing memory with same topic_key
-	Tags       []string          // Tags for categorization
-	Type       MemoryType        // Type of memory
-	Trust      TrustLevel        // Initial trust level
-	Project    string            // Project scope
-	Source     string            // Origin (e.g., "cli", "agent:claude")
-	ExtraData  map[string]string // Additional metadata
+	TopicKey       string            // If set, updates existing memory with same topic_key
+	Tags           []string          // Tags for categorization
+	Type           MemoryType        // Type of memory
+	Trust          TrustLevel        // Initial trust level
+	Project        string            // Project scope
+	Source         string            // Origin (e.g., "cli", "agent:claude")
+	ExtraData      map[string]string // Additional metadata
+	AllowDuplicate bool              // Store even if a near-duplicate exists
+}
+
+// UpdateOptions holds the changes to make to a memory; empty fields are left as they are
+type UpdateOptions struct {
+	Content string     // New content
+	Type    MemoryType // New type
+	Tags    []string   // Replaces the tags if not nil
+	Source  string     // Author of the change (e.g., "cli", "agent:mcp")
+}
+
+// StoreAction reports what Store did with new content
+type StoreAction string
+
+const (
+	StoreCreated StoreAction = "created" // Stored as a new memory
+	StoreUpdated StoreAction = "updated" // Replaced the memory with the same topic key
+	StoreRefused StoreAction = "refused" // Not stored: a near-duplicate exists
+	StoreMerged  StoreAction = "merged"  // Not stored: the near-duplicate was bumped instead
+	StoreRevised StoreAction = "revised" // Became a new revision of the near-duplicate
+	StoreRelated StoreAction = "related" // Stored and related to the near-duplicate
+)
+
+// StoreResult is the outcome of storing content
+type StoreResult struct {
+	Memory     *Memory     `json:"memory,omitempty"` // Memory holding the content (nil if refused)
+	Action     StoreAction `json:"action"`
+	Duplicate  *Memory     `json:"duplicate,omitempty"`  // Near-duplicate the content collided with
+	Similarity float64     `json:"similarity,omitempty"` // Cosine similarity to the duplicate
 }
 
 // RecallOptions configures how memories are searched
 type RecallOptions struct {
-	Limit      int        // Max results (default: 5)
-	MinScore   float64    // Minimum relevance score (default: 0.3)
-	Types      []MemoryType // Filter by type
-	Tags       []string   // Filter by tags
+	Limit       int          // Max results (default: 5)
+	MinScore    float64      // Minimum similarity of a memory found by vector search only (default: 0.3)
+	Types       []MemoryType // Filter by type
+	Tags        []string     // Filter by tags
 	TrustLevels []TrustLevel // Filter by trust (default: validated+)
-	Project    string     // Filter by project
-	TopicKey   string     // Filter by topic key prefix
+	Project     string       // Filter by project
+	TopicKey    string       // Filter by topic key prefix
+	Rerank      bool         // Rescore the top candidates with the configured reranker
+	Explain     bool         // Attach an Explanation to each result
+	MMRLambda   float64      // If set, diversify results by MMR: 1 favors relevance, lower values favor variety
 }
 
 // Config holds Cortex configuration
 type Config struct {
-	DBPath           string `json:"db_path"`
-	EmbeddingProvider string `json:"embedding_provider"` // "openai" or "ollama"
-	OpenAIKey        string `json:"openai_key,omitempty"`
-	OllamaURL        string `json:"ollama_url,omitempty"`
-	OllamaModel      string `json:"ollama_model,omitempty"`
-	DefaultProject   string `json:"default_project,omitempty"`
+	DBPath            string         `json:"db_path"`
+	EmbeddingProvider string         `json:"embedding_provider"` // "openai" or "ollama"
+	OpenAIKey         string         `json:"openai_key,omitempty"`
+	OllamaURL         string         `json:"ollama_url,omitempty"`
+	OllamaModel       string         `json:"ollama_model,omitempty"`
+	DefaultProject    string         `json:"default_project,omitempty"`
+	Stores            []StoreConfig  `json:"stores,omitempty"`        // Extra stores searched alongside DBPath
+	DefaultStore      string         `json:"default_store,omitempty"` // Store that receives writes (default: "project")
+	Ranking           *RankingConfig `json:"ranking,omitempty"`       // How recall combines semantic and keyword matches
+	Rerank            *RerankConfig  `json:"rerank,omitempty"`        // Reranker used by recall --rerank
+	Scoring           *ScoringConfig `json:"scoring,omitempty"`       // How recall boosts trusted, popular and fresh memories
+	Dedupe            *DedupeConfig  `json:"dedupe,omitempty"`        // What store does with near-duplicates
+}
+
+// DedupeConfig controls the near-duplicate check made when storing
+type DedupeConfig struct {
+	Threshold float64 `json:"threshold,omitempty"` // Cosine similarity at which content counts as a duplicate (default: 0.95)
+	Action    string  `json:"action,omitempty"`    // "relate" (default), "merge", "refuse", "revise" or "off"
+}
+
+// ScoringConfig gives part of each recall score to how battle-tested a memory
+// is rather than how well it matches. Each weight is the share of the score
+// taken by its factor; relevance keeps the rest. Omit it for the defaults, or
+// set a weight to 0 to turn that factor off.
+type ScoringConfig struct {
+	TrustWeight   float64 `json:"trust_weight"`             // Trust level: proposed 0, validated 0.5, proven 1
+	AccessWeight  float64 `json:"access_weight"`            // Access count, log-scaled, full at 100 accesses
+	RecencyWeight float64 `json:"recency_weight"`           // Age of updated_at, halving every HalfLifeDays
+	HalfLifeDays  float64 `json:"half_life_days,omitempty"` // Default: 30
+}
+
+// RerankConfig selects and tunes the reranker applied to recall candidates
+type RerankConfig struct {
+	Provider   string  `json:"provider,omitempty"`   // "heuristic" (default) or "llm"
+	Model      string  `json:"model,omitempty"`      // Chat model for "llm" (default: gpt-4o-mini)
+	BaseURL    string  `json:"base_url,omitempty"`   // OpenAI-compatible API for "llm" (default: OpenAI)
+	APIKey     string  `json:"api_key,omitempty"`    // Key for "llm" (default: openai_key)
+	Candidates int     `json:"candidates,omitempty"` // How many top candidates to rerank (default: 20)
+	Weight     float64 `json:"weight,omitempty"`     // Share of the final score given to the reranker, 0-1 (default: 0.7)
+}
+
+// RankingConfig tunes how recall fuses vector similarity with BM25 keyword
+// scores. Raise KeywordWeight for code-heavy content such as error messages
+// and stack traces, where exact tokens matter more than meaning.
+type RankingConfig struct {
+	Method         string  `json:"method,omitempty"`          // "weighted" (default) or "rrf"
+	SemanticWeight float64 `json:"semantic_weight,omitempty"` // Default: 0.7
+	KeywordWeight  float64 `json:"keyword_weight,omitempty"`  // Default: 0.3
+	RRFK           float64 `json:"rrf_k,omitempty"`           // Rank offset for "rrf" (default: 60)
+}
+
+// StoreConfig describes an additional memory store, such as a team store in a
+// git-tracked directory or a global store under ~/.cortex
+type StoreConfig struct {
+	Name     string  `json:"name"`                // e.g., "team", "global"
+	Path     string  `json:"path"`                // Database file, or a directory holding cortex.db
+	ReadOnly bool    `json:"read_only,omitempty"` // Never write to this store
+	Weight   float64 `json:"weight,omitempty"`    // Score multiplier in recall (default: 1.0)
+	SyncDir  string  `json:"sync_dir,omitempty"`  // Markdown mirror for 'cortex sync' (default: memories/ next to the database)
+}
+
+// ExportRecord is one line of a JSONL export. Kind tells which field is set.
+type ExportRecord struct {
+	Kind      string           `json:"kind"` // "header", "memory", "revision", "relation", "embedding"
+	Header    *ExportHeader    `json:"header,omitempty"`
+	Memory    *Memory          `json:"memory,omitempty"`
+	Revision  *Revision        `json:"revision,omitempty"`
+	Relation  *Relation        `json:"relation,omitempty"`
+	Embedding *ExportEmbedding `json:"embedding,omitempty"`
+}
+
+// ExportHeader is the first record of an export
+type ExportHeader struct {
+	Format              string    `json:"format"` // Always "cortex-export"
+	Version             int       `json:"version"`
+	ExportedAt          time.Time `json:"exported_at"`
+	EmbeddingModel      string    `json:"embedding_model,omitempty"`
+	EmbeddingDimensions int       `json:"embedding_dimensions,omitempty"`
+}
+
+// ExportEmbedding carries a memory's vector and the model that produced it
+type ExportEmbedding struct {
+	MemoryID string    `json:"memory_id"`
+	Model    string    `json:"model"`
+	Vector   []float32 `json:"vector"`
 }